@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// 每轮最多刷新的 dirty 帐户数
+const dirtyAccountBatchSize = 500
+
+// collectAddresses 从 ABI 解码结果中提取地址类型的参数
+func collectAddresses(values []interface{}) []string {
+	addresses := make([]string, 0)
+	for _, v := range values {
+		switch value := v.(type) {
+		case common.Address:
+			addresses = append(addresses, strings.ToLower(value.Hex()))
+		case []common.Address:
+			for _, address := range value {
+				addresses = append(addresses, strings.ToLower(address.Hex()))
+			}
+		}
+	}
+	return addresses
+}
+
+// eventAddresses 按合约 ABI 解码回执中的事件，返回 indexed 和非 indexed 的地址参数
+func eventAddresses(contractAbi abi.ABI, logs []LogEntry) []string {
+	addresses := make([]string, 0)
+	for _, entry := range logs {
+		if !strings.EqualFold(entry.Address, contractAddress) || len(entry.Topics) == 0 {
+			continue
+		}
+		event, err := contractAbi.EventByID(common.HexToHash(entry.Topics[0]))
+		if err != nil {
+			continue
+		}
+		topicIndex := 1
+		for _, input := range event.Inputs {
+			if !input.Indexed {
+				continue
+			}
+			if topicIndex < len(entry.Topics) && input.Type.T == abi.AddressTy {
+				addresses = append(addresses, strings.ToLower(common.HexToAddress(entry.Topics[topicIndex]).Hex()))
+			}
+			topicIndex++
+		}
+		data, err := hexutil.Decode(entry.Data)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		values, err := event.Inputs.NonIndexed().Unpack(data)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, collectAddresses(values)...)
+	}
+	return addresses
+}
+
+// markAccountsDirty 标记帐户在 blockNum 发生变化需要刷新，只影响 bc_block_account 中已有的帐户。
+// dirty_block 记录标记帐户的区块，刷新前被多个区块标记时取最高的区块，作为帐户历史的 block_num
+func (s *SQL) markAccountsDirty(blockNum int, addresses []string) {
+	seen := make(map[string]bool)
+	args := make([]interface{}, 0, len(addresses)+2)
+	args = append(args, blockNum, blockNum)
+	for _, address := range addresses {
+		if address == "" || seen[address] {
+			continue
+		}
+		seen[address] = true
+		args = append(args, address)
+	}
+	if len(args) == 2 {
+		return
+	}
+	// MySQL 按顺序赋值，dirty_block 需要在 dirty 之前计算
+	_, err := s.Exec("UPDATE bc_block_account SET dirty_block = CASE WHEN dirty = 1 AND dirty_block > ? THEN dirty_block ELSE ? END, dirty = 1 WHERE address IN (?"+
+		strings.Repeat(", ?", len(args)-3)+")", args...)
+	if err != nil {
+		logger.Error("标记帐户失败", logKeyBlockNum, blockNum, "count", len(args)-2, "error", err)
+	}
+}
+
+func (s *SQL) clearAccountDirty(address string) {
+	_, err := s.Exec("UPDATE bc_block_account SET dirty = 0 WHERE address = ?", address)
+	if err != nil {
+		logger.Error("清除帐户标记失败", logKeyAddress, address, "error", err)
+	}
+}
+
+// synDirtyAccountTask 刷新 dirty 帐户。先清除标记再刷新，
+// 刷新过程中再次被标记的帐户会在下一轮处理
+func (s *SQL) synDirtyAccountTask() {
+	rows, err := s.Query("SELECT address, dirty_block FROM bc_block_account WHERE dirty = 1 LIMIT ?", dirtyAccountBatchSize)
+	if err != nil {
+		logger.Error("查询帐户失败", "error", err)
+		return
+	}
+	accounts := make([]string, 0)
+	blocks := make([]int, 0)
+	for rows.Next() {
+		var address string
+		var blockNum int
+		if err := rows.Scan(&address, &blockNum); err != nil {
+			rows.Close()
+			logger.Error("查询帐户失败", "error", err)
+			return
+		}
+		accounts = append(accounts, address)
+		blocks = append(blocks, blockNum)
+	}
+	rows.Close()
+
+	if len(accounts) > 0 {
+		logger.Debug("刷新帐户", "count", len(accounts))
+	}
+	for i, address := range accounts {
+		s.clearAccountDirty(address)
+		if err := s.synUpdateAccount(address, blocks[i]); err != nil {
+			// 重新标记，下一轮重试
+			logger.Error("刷新帐户失败", logKeyAddress, address, "error", err)
+			s.markAccountsDirty(blocks[i], []string{address})
+		}
+	}
+}
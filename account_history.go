@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单次查询最多读取的历史记录数
+const accountHistoryMaxRows = 100000
+
+// AccountHistory 帐户 balance/cred/share_num 变化记录，对应 bc_account_history 表
+type AccountHistory struct {
+	Address     string `json:"address"`
+	BlockNum    int    `json:"block_num"`
+	OldBalance  int    `json:"old_balance"`
+	NewBalance  int    `json:"new_balance"`
+	OldCred     int    `json:"old_cred"`
+	NewCred     int    `json:"new_cred"`
+	OldShareNum int    `json:"old_share_num"`
+	NewShareNum int    `json:"new_share_num"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+type AccountHistoryList struct {
+	Address  string           `json:"address"`
+	Start    int64            `json:"start"`
+	End      int64            `json:"end"`
+	Interval string           `json:"interval"`
+	List     []AccountHistory `json:"list"`
+}
+
+// insertAccountHistory 记录一次帐户变化，block_num 为标记帐户变化的区块，未知时取当前已同步的最新区块高度
+func (s *SQL) insertAccountHistory(h AccountHistory) {
+	if h.BlockNum <= 0 {
+		err := s.QueryRow("SELECT COALESCE(MAX(block_num), 0) FROM bc_block_number").Scan(&h.BlockNum)
+		if err != nil {
+			logger.Error("查询区块高度失败", logKeyAddress, h.Address, "error", err)
+		}
+	}
+	h.Address = strings.ToLower(h.Address)
+	h.CreatedAt = time.Now().UnixMilli()
+	_, err := s.Exec("INSERT INTO bc_account_history (address, block_num, old_balance, new_balance, old_cred, new_cred, old_share_num, new_share_num, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		h.Address, h.BlockNum, h.OldBalance, h.NewBalance, h.OldCred, h.NewCred, h.OldShareNum, h.NewShareNum, h.CreatedAt)
+	if err != nil {
+		logger.Error("记录帐户历史失败", logKeyAddress, h.Address, "error", err)
+	}
+}
+
+// parseInterval 解析降采样间隔，支持 time.ParseDuration 格式以及 1d、7d 这样的天数
+func parseInterval(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	return d, nil
+}
+
+// downsampleAccountHistory 按时间间隔合并记录：每个区间取第一条的旧值和最后一条的新值
+func downsampleAccountHistory(list []AccountHistory, interval time.Duration) []AccountHistory {
+	step := interval.Milliseconds()
+	result := make([]AccountHistory, 0)
+	for _, h := range list {
+		bucket := h.CreatedAt - h.CreatedAt%step
+		if n := len(result); n > 0 && result[n-1].CreatedAt == bucket {
+			last := &result[n-1]
+			last.BlockNum = h.BlockNum
+			last.NewBalance = h.NewBalance
+			last.NewCred = h.NewCred
+			last.NewShareNum = h.NewShareNum
+			continue
+		}
+		h.CreatedAt = bucket
+		result = append(result, h)
+	}
+	return result
+}
+
+// getAccountHistory 查询地址的 balance/cred/share_num 变化历史，
+// start/end 为毫秒时间戳，interval（如 1h、1d）不为空时按区间降采样
+func (srv *Server) getAccountHistory(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	address := strings.ToLower(queryValues.Get("address"))
+	if !isValidAddress(address) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidAddress, "Invalid address format")
+		return
+	}
+	end, err := strconv.ParseInt(queryValues.Get("end"), 10, 64)
+	if err != nil || end <= 0 {
+		end = time.Now().UnixMilli()
+	}
+	start, err := strconv.ParseInt(queryValues.Get("start"), 10, 64)
+	if err != nil || start < 0 {
+		start = end - 30*24*time.Hour.Milliseconds()
+	}
+	var interval time.Duration
+	if v := queryValues.Get("interval"); v != "" {
+		interval, err = parseInterval(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errCodeInvalidParameter, err.Error())
+			return
+		}
+	}
+
+	list, err := srv.accounts.History(address, start, end, accountHistoryMaxRows)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	if interval > 0 {
+		list = downsampleAccountHistory(list, interval)
+	}
+
+	response := ResponseList{
+		Data: AccountHistoryList{
+			Address:  address,
+			Start:    start,
+			End:      end,
+			Interval: queryValues.Get("interval"),
+			List:     list,
+		},
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// AccountProfile 帐户概况，由 bc_block_account、bc_block_transactions、注册记录和排行榜组合而成
+type AccountProfile struct {
+	Address        string              `json:"address"`
+	Balance        int                 `json:"balance"`
+	Cred           int                 `json:"cred"`
+	ShareNnum      int                 `json:"share_num"`
+	Rank           map[string]int      `json:"rank"`
+	Registration   AccountRegistration `json:"registration"`
+	FirstSeenBlock int                 `json:"first_seen_block"`
+	LastSeenBlock  int                 `json:"last_seen_block"`
+	TotalTxCount   int                 `json:"total_tx_count"`
+	SuccessTxCount int                 `json:"success_tx_count"`
+	LatestVitals   *VitalsSummary      `json:"latest_vitals"`
+	Person         *LinkedPerson       `json:"person"`
+}
+
+type AccountRegistration struct {
+	Registered   bool   `json:"registered"`
+	RegisteredAt int64  `json:"registered_at"`
+	TxHash       string `json:"tx_hash"`
+	JobId        int64  `json:"job_id,omitempty"`
+	JobStatus    string `json:"job_status,omitempty"`
+}
+
+// VitalsSummary 最近一次 shareData 上报的体征数据
+type VitalsSummary struct {
+	Hash           string `json:"trans_hash"`
+	BlockNumber    int    `json:"block_num"`
+	ImportTime     int    `json:"import_time"`
+	HeartRate      string `json:"heart_rate"`
+	BreathRate     string `json:"breath_rate"`
+	SleepState     int    `json:"sleep_state"`
+	HeartChange    string `json:"heart_change"`
+	SleepBreathing string `json:"sleep_breathing"`
+}
+
+type LinkedPerson struct {
+	PersonId        int    `json:"person_id"`
+	ContactName     string `json:"contact_name"`
+	ContactIdentity string `json:"contact_identity"`
+}
+
+// accountProfile 组装帐户概况，帐户和交易都不存在时返回 sql.ErrNoRows
+func (srv *Server) accountProfile(address string) (*AccountProfile, error) {
+	address = strings.ToLower(address)
+	p := &AccountProfile{Address: address, Rank: make(map[string]int)}
+	account, err := srv.accounts.Get(address)
+	accountExists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if accountExists {
+		p.Address, p.Balance, p.Cred, p.ShareNnum = account.Address, account.Balance, account.Cred, account.ShareNnum
+	}
+
+	activity, err := srv.txs.ActivityOf(address)
+	if err != nil {
+		return nil, err
+	}
+	p.TotalTxCount, p.SuccessTxCount = activity.TotalTxCount, activity.SuccessTxCount
+	p.FirstSeenBlock, p.LastSeenBlock = activity.FirstSeenBlock, activity.LastSeenBlock
+	if !accountExists && p.LastSeenBlock == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if accountExists {
+		for key := range rankingSortKeys {
+			if loaded, _, _ := leaderboard.state(); loaded {
+				p.Rank[key] = leaderboard.rank(key, p.Address)
+				continue
+			}
+			p.Rank[key], err = srv.accounts.Rank(RankingOptions{Sort: key, Window: "all"}, p.Address)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.Registration, err = srv.accounts.Registration(address)
+	if err != nil {
+		return nil, err
+	}
+
+	p.LatestVitals, err = srv.txs.LatestVitals(address)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	p.Person, err = srv.txs.LatestPerson(address)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (srv *Server) getAccountProfile(w http.ResponseWriter, r *http.Request, address string) {
+	profile, err := srv.accountProfile(address)
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "Account not found")
+		return
+	}
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+
+	response := ResponseList{
+		Data: profile,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
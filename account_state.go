@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// 历史状态的数据来源
+const (
+	stateSourceHistory = "history"
+	stateSourceNode    = "node"
+)
+
+var errStateNotAvailable = errors.New("state at this block is not available")
+
+// AccountStateResponse 帐户在某个区块高度的状态
+type AccountStateResponse struct {
+	Address   string `json:"address"`
+	BlockNum  int    `json:"block_num"`
+	Balance   int    `json:"balance"`
+	Cred      int    `json:"cred"`
+	ShareNnum int    `json:"share_num"`
+	Source    string `json:"source"`
+}
+
+// accountStateFromNode 在节点支持历史调用（RPC_HISTORICAL_CALL=on）时，按区块高度调用合约查询。
+// 节点不支持时返回 errStateNotAvailable，节点和数据库错误分别返回 errCodeNode、errCodeDatabase 的 ServiceError
+func (srv *Server) accountStateFromNode(address string, blockNum int) (*AccountStateResponse, error) {
+	if !rpcHistoricalCall {
+		return nil, errStateNotAvailable
+	}
+	balance, err := callContractUint("balance", address, blockNum)
+	if err != nil {
+		return nil, &ServiceError{Code: errCodeNode, Msg: "Error querying node", Err: err}
+	}
+	cred, err := callContractUint("cred", address, blockNum)
+	if err != nil {
+		return nil, &ServiceError{Code: errCodeNode, Msg: "Error querying node", Err: err}
+	}
+	// share_num 由本地交易统计得出
+	shareNum, err := srv.txs.ShareCountAt(address, blockNum)
+	if err != nil {
+		return nil, databaseError("Error querying account state", err)
+	}
+	return &AccountStateResponse{
+		Address:   address,
+		BlockNum:  blockNum,
+		Balance:   int(balance),
+		Cred:      int(cred),
+		ShareNnum: shareNum,
+		Source:    stateSourceNode,
+	}, nil
+}
+
+// callContractUint 调用合约的只读方法 method(address) returns (uint)，
+// blockNum 大于 0 时作为历史区块高度传给节点
+func callContractUint(method string, address string, blockNum int) (int64, error) {
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return 0, err
+	}
+	data, err := contractAbi.Pack(method, common.HexToAddress(address))
+	if err != nil {
+		return 0, err
+	}
+	params := []interface{}{contractAddress, hexutil.Encode(data)}
+	if blockNum > 0 {
+		params = append(params, blockNum)
+	}
+	result, err := rpcCall("call", params...)
+	if err != nil {
+		return 0, err
+	}
+	var receipt TransactionReceipt
+	err = json.Unmarshal(result, &receipt)
+	if err != nil {
+		return 0, err
+	}
+	if receipt.Status != 0 {
+		return 0, fmt.Errorf("call %s failed with status %d", method, receipt.Status)
+	}
+	output, err := hexutil.Decode(receipt.Output)
+	if err != nil {
+		return 0, err
+	}
+	return new(big.Int).SetBytes(output).Int64(), nil
+}
+
+// getAccount 处理 /account/{address}，不带 block 参数时返回帐户概况，带 block 参数时返回该区块高度的 balance 和 cred
+func (srv *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	address := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/account/"))
+	if !isValidAddress(address) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidAddress, "Invalid address format")
+		return
+	}
+
+	blockStr := r.URL.Query().Get("block")
+	if blockStr == "" {
+		srv.getAccountProfile(w, r, address)
+		return
+	}
+	blockNum, err := strconv.Atoi(blockStr)
+	if err != nil || blockNum < 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Invalid block number")
+		return
+	}
+
+	state, err := srv.accounts.StateAt(address, blockNum)
+	if err == errStateNotAvailable {
+		state, err = srv.accountStateFromNode(address, blockNum)
+	} else if err != nil {
+		err = databaseError("Error querying account state", err)
+	}
+	if err == errStateNotAvailable {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "State at this block is not available")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := ResponseList{
+		Data: state,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
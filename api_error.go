@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// 机器可读的错误码，与 HTTP 状态码一起返回
+const (
+	errCodeBadRequest          = "bad_request"
+	errCodeInvalidParameter    = "invalid_parameter"
+	errCodeInvalidAddress      = "invalid_address"
+	errCodeInvalidHash         = "invalid_hash"
+	errCodeUnauthorized        = "unauthorized"
+	errCodeNotFound            = "not_found"
+	errCodeMethodNotAllowed    = "method_not_allowed"
+	errCodePayloadTooLarge     = "payload_too_large"
+	errCodeTransactionReverted = "transaction_reverted"
+	errCodeDatabase            = "database_error"
+	errCodeNode                = "node_error"
+	errCodeInternal            = "internal_error"
+	errCodeNotReady            = "not_ready"
+)
+
+// ErrorResponse 所有接口统一的错误响应。code 固定为 0（成功响应为 1），
+// error 为机器可读的错误码，msg 为可读的说明，request_id 与响应头 X-Request-Id 相同
+type ErrorResponse struct {
+	Code      int         `json:"code"`
+	Msg       string      `json:"msg"`
+	Error     string      `json:"error"`
+	RequestId string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// writeError 按统一格式返回错误
+func writeError(w http.ResponseWriter, status int, code string, msg string) {
+	writeErrorData(w, status, code, msg, nil)
+}
+
+// writeErrorData 返回错误并附带数据，例如链上执行失败时的交易哈希
+func writeErrorData(w http.ResponseWriter, status int, code string, msg string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:      0,
+		Msg:       msg,
+		Error:     code,
+		RequestId: w.Header().Get("X-Request-Id"),
+		Data:      data,
+	})
+}
+
+// writeDatabaseError 记录并返回数据库错误
+func writeDatabaseError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	loggerFromContext(r.Context()).Error(msg, "error", err)
+	writeError(w, http.StatusInternalServerError, errCodeDatabase, msg)
+}
+
+// errCodeHTTPStatus 服务层错误码对应的 HTTP 状态码，未列出的按 500 处理
+var errCodeHTTPStatus = map[string]int{
+	errCodeBadRequest:          http.StatusBadRequest,
+	errCodeInvalidParameter:    http.StatusBadRequest,
+	errCodeInvalidAddress:      http.StatusBadRequest,
+	errCodeInvalidHash:         http.StatusBadRequest,
+	errCodeUnauthorized:        http.StatusUnauthorized,
+	errCodeNotFound:            http.StatusNotFound,
+	errCodeMethodNotAllowed:    http.StatusMethodNotAllowed,
+	errCodePayloadTooLarge:     http.StatusRequestEntityTooLarge,
+	errCodeTransactionReverted: http.StatusUnprocessableEntity,
+	errCodeNode:                http.StatusBadGateway,
+	errCodeNotReady:            http.StatusServiceUnavailable,
+}
+
+// writeServiceError 按错误码返回服务层错误，带底层错误时记录日志
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	se := asServiceError(err)
+	if se.Err != nil {
+		loggerFromContext(r.Context()).Error(se.Msg, "error", se.Err)
+	}
+	status, ok := errCodeHTTPStatus[se.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeError(w, status, se.Code, se.Msg)
+}
+
+// handleNotFound 未注册的路径
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, errCodeNotFound, "Not Found")
+}
@@ -0,0 +1,344 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: bc.proto
+
+package bcpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	BcService_Register_FullMethodName           = "/bc.v1.BcService/Register"
+	BcService_GetContractAddress_FullMethodName = "/bc.v1.BcService/GetContractAddress"
+	BcService_ListTransactions_FullMethodName   = "/bc.v1.BcService/ListTransactions"
+	BcService_GetRanking_FullMethodName         = "/bc.v1.BcService/GetRanking"
+	BcService_GetAccountRank_FullMethodName     = "/bc.v1.BcService/GetAccountRank"
+	BcService_WatchBlocks_FullMethodName        = "/bc.v1.BcService/WatchBlocks"
+)
+
+// BcServiceClient is the client API for BcService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BcService 与 HTTP 接口共用同一套服务层：注册、合约地址、交易列表、排行榜，另提供新区块推送
+type BcServiceClient interface {
+	// Register 提交注册任务，同 POST /register
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterJob, error)
+	// GetContractAddress 同 GET /contract-address
+	GetContractAddress(ctx context.Context, in *GetContractAddressRequest, opts ...grpc.CallOption) (*GetContractAddressResponse, error)
+	// ListTransactions 同 GET /getTransByAddress、/getResByAddress，返回全部体征字段
+	ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error)
+	// GetRanking 同 GET /accountRanking
+	GetRanking(ctx context.Context, in *GetRankingRequest, opts ...grpc.CallOption) (*GetRankingResponse, error)
+	// GetAccountRank 同 GET /accountRanking/{address}
+	GetAccountRank(ctx context.Context, in *GetAccountRankRequest, opts ...grpc.CallOption) (*GetAccountRankResponse, error)
+	// WatchBlocks 按高度升序推送已同步的区块
+	WatchBlocks(ctx context.Context, in *WatchBlocksRequest, opts ...grpc.CallOption) (BcService_WatchBlocksClient, error)
+}
+
+type bcServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBcServiceClient(cc grpc.ClientConnInterface) BcServiceClient {
+	return &bcServiceClient{cc}
+}
+
+func (c *bcServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterJob, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterJob)
+	err := c.cc.Invoke(ctx, BcService_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bcServiceClient) GetContractAddress(ctx context.Context, in *GetContractAddressRequest, opts ...grpc.CallOption) (*GetContractAddressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetContractAddressResponse)
+	err := c.cc.Invoke(ctx, BcService_GetContractAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bcServiceClient) ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTransactionsResponse)
+	err := c.cc.Invoke(ctx, BcService_ListTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bcServiceClient) GetRanking(ctx context.Context, in *GetRankingRequest, opts ...grpc.CallOption) (*GetRankingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRankingResponse)
+	err := c.cc.Invoke(ctx, BcService_GetRanking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bcServiceClient) GetAccountRank(ctx context.Context, in *GetAccountRankRequest, opts ...grpc.CallOption) (*GetAccountRankResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAccountRankResponse)
+	err := c.cc.Invoke(ctx, BcService_GetAccountRank_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bcServiceClient) WatchBlocks(ctx context.Context, in *WatchBlocksRequest, opts ...grpc.CallOption) (BcService_WatchBlocksClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BcService_ServiceDesc.Streams[0], BcService_WatchBlocks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bcServiceWatchBlocksClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BcService_WatchBlocksClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type bcServiceWatchBlocksClient struct {
+	grpc.ClientStream
+}
+
+func (x *bcServiceWatchBlocksClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BcServiceServer is the server API for BcService service.
+// All implementations must embed UnimplementedBcServiceServer
+// for forward compatibility
+//
+// BcService 与 HTTP 接口共用同一套服务层：注册、合约地址、交易列表、排行榜，另提供新区块推送
+type BcServiceServer interface {
+	// Register 提交注册任务，同 POST /register
+	Register(context.Context, *RegisterRequest) (*RegisterJob, error)
+	// GetContractAddress 同 GET /contract-address
+	GetContractAddress(context.Context, *GetContractAddressRequest) (*GetContractAddressResponse, error)
+	// ListTransactions 同 GET /getTransByAddress、/getResByAddress，返回全部体征字段
+	ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error)
+	// GetRanking 同 GET /accountRanking
+	GetRanking(context.Context, *GetRankingRequest) (*GetRankingResponse, error)
+	// GetAccountRank 同 GET /accountRanking/{address}
+	GetAccountRank(context.Context, *GetAccountRankRequest) (*GetAccountRankResponse, error)
+	// WatchBlocks 按高度升序推送已同步的区块
+	WatchBlocks(*WatchBlocksRequest, BcService_WatchBlocksServer) error
+	mustEmbedUnimplementedBcServiceServer()
+}
+
+// UnimplementedBcServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBcServiceServer struct {
+}
+
+func (UnimplementedBcServiceServer) Register(context.Context, *RegisterRequest) (*RegisterJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedBcServiceServer) GetContractAddress(context.Context, *GetContractAddressRequest) (*GetContractAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContractAddress not implemented")
+}
+func (UnimplementedBcServiceServer) ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+}
+func (UnimplementedBcServiceServer) GetRanking(context.Context, *GetRankingRequest) (*GetRankingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRanking not implemented")
+}
+func (UnimplementedBcServiceServer) GetAccountRank(context.Context, *GetAccountRankRequest) (*GetAccountRankResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccountRank not implemented")
+}
+func (UnimplementedBcServiceServer) WatchBlocks(*WatchBlocksRequest, BcService_WatchBlocksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBlocks not implemented")
+}
+func (UnimplementedBcServiceServer) mustEmbedUnimplementedBcServiceServer() {}
+
+// UnsafeBcServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BcServiceServer will
+// result in compilation errors.
+type UnsafeBcServiceServer interface {
+	mustEmbedUnimplementedBcServiceServer()
+}
+
+func RegisterBcServiceServer(s grpc.ServiceRegistrar, srv BcServiceServer) {
+	s.RegisterService(&BcService_ServiceDesc, srv)
+}
+
+func _BcService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BcServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BcService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BcServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BcService_GetContractAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContractAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BcServiceServer).GetContractAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BcService_GetContractAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BcServiceServer).GetContractAddress(ctx, req.(*GetContractAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BcService_ListTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BcServiceServer).ListTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BcService_ListTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BcServiceServer).ListTransactions(ctx, req.(*ListTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BcService_GetRanking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRankingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BcServiceServer).GetRanking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BcService_GetRanking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BcServiceServer).GetRanking(ctx, req.(*GetRankingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BcService_GetAccountRank_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRankRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BcServiceServer).GetAccountRank(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BcService_GetAccountRank_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BcServiceServer).GetAccountRank(ctx, req.(*GetAccountRankRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BcService_WatchBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BcServiceServer).WatchBlocks(m, &bcServiceWatchBlocksServer{ServerStream: stream})
+}
+
+type BcService_WatchBlocksServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type bcServiceWatchBlocksServer struct {
+	grpc.ServerStream
+}
+
+func (x *bcServiceWatchBlocksServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BcService_ServiceDesc is the grpc.ServiceDesc for BcService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BcService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bc.v1.BcService",
+	HandlerType: (*BcServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _BcService_Register_Handler,
+		},
+		{
+			MethodName: "GetContractAddress",
+			Handler:    _BcService_GetContractAddress_Handler,
+		},
+		{
+			MethodName: "ListTransactions",
+			Handler:    _BcService_ListTransactions_Handler,
+		},
+		{
+			MethodName: "GetRanking",
+			Handler:    _BcService_GetRanking_Handler,
+		},
+		{
+			MethodName: "GetAccountRank",
+			Handler:    _BcService_GetAccountRank_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchBlocks",
+			Handler:       _BcService_WatchBlocks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bc.proto",
+}
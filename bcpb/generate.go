@@ -0,0 +1,4 @@
+// Package bcpb gRPC 接口定义，由 bc.proto 生成
+package bcpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative bc.proto
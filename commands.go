@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// command 一个子命令，run 返回进程退出码
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+// verify 未指定范围时检查的最新区块数，以及每批从本地索引读取的区块数
+const (
+	verifyDefaultBlocks = 100
+	verifyBatchSize     = 200
+)
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"migrate", "migrate [flags]                         执行建表后退出", runMigrate},
+		{"serve", "serve [flags]                           只启动 HTTP、gRPC 接口，不运行后台任务，不建表", runServe},
+		{"sync", "sync [flags]                            只运行区块同步、帐户同步和注册任务，不建表，开关默认为 on", runSync},
+		{"backfill", "backfill -from N -to M [flags]          同步区间内（含两端）尚未入库的区块后退出", runBackfill},
+		{"verify", "verify [-from N] [-to M] [flags]        对比本地索引与节点的区块，默认检查最新 100 个区块", runVerify},
+		{"account", "account refresh [flags] <address>       从链上刷新帐户的 balance、cred、share_num", runAccount},
+		{"config", "config print [-format yaml|toml|env] [flags]  输出生效的配置", runConfigCommand},
+	}
+}
+
+// runCommand 按第一个参数选择子命令；没有子命令（或第一个参数是参数）时与之前一样
+// 在同一进程中执行建表、启动后台任务和接口
+func runCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runAll(args)
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	}
+	printUsage()
+	if args[0] == "help" {
+		return 0
+	}
+	return 2
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: bc_server [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\n不带命令时执行建表并在同一进程中运行接口和后台任务（后台任务按 PULL_TASK_STATUS、ACCOUNT_TASK_STATUS 开关）。\n\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, "  "+cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\n各命令都接受配置参数，bc_server <command> -h 查看")
+}
+
+// setupCommand 解析参数、加载并校验配置后初始化日志，返回位置参数
+func setupCommand(fs *flag.FlagSet, args []string, base *Config) ([]string, error) {
+	c, rest, err := loadConfig(fs, args, base)
+	if err == nil {
+		err = c.validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.apply()
+	initLogger()
+	return rest, nil
+}
+
+// setupExitCode 参数或配置有误时的退出码，-h 时为 0
+func setupExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return 2
+}
+
+func runAll(args []string) int {
+	fs := flag.NewFlagSet("bc_server", flag.ContinueOnError)
+	fs.Usage = func() {
+		printUsage()
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+	if _, err := setupCommand(fs, args, defaultConfig()); err != nil {
+		return setupExitCode(err)
+	}
+	s := initDB()
+	srv := NewServer(s)
+	initBlockTask(s)
+	initLeaderboard(srv.accounts)
+	initStats(srv)
+	initGRPC(srv)
+	initListen(srv)
+	return 0
+}
+
+func runMigrate(args []string) int {
+	if _, err := setupCommand(flag.NewFlagSet("migrate", flag.ContinueOnError), args, defaultConfig()); err != nil {
+		return setupExitCode(err)
+	}
+	initDB()
+	return 0
+}
+
+// runServe 只提供接口，不执行建表，需要先运行 migrate。注册任务写入数据库队列，由 sync 进程执行；
+// 排行榜按 LEADERBOARD_RELOAD_MINUTES 重新加载
+func runServe(args []string) int {
+	if _, err := setupCommand(flag.NewFlagSet("serve", flag.ContinueOnError), args, defaultConfig()); err != nil {
+		return setupExitCode(err)
+	}
+	s := NewSQL()
+	srv := NewServer(s)
+	initLeaderboard(srv.accounts)
+	initStats(srv)
+	initGRPC(srv)
+	initListen(srv)
+	return 0
+}
+
+// runSync 只运行后台任务，不执行建表，收到 SIGINT、SIGTERM 时退出
+func runSync(args []string) int {
+	base := defaultConfig()
+	base.Tasks.Pull = true
+	base.Tasks.Account = true
+	if _, err := setupCommand(flag.NewFlagSet("sync", flag.ContinueOnError), args, base); err != nil {
+		return setupExitCode(err)
+	}
+	s := NewSQL()
+	initBlockTask(s)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	logger.Info("同步任务退出")
+	return 0
+}
+
+// runBackfill 同步 [from, to] 中 bc_block_number 里还没有的区块，已入库的区块跳过；
+// 有区块同步失败时返回 1
+func runBackfill(args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.Int("from", 0, "起始区块（含）")
+	to := fs.Int("to", 0, "结束区块（含）")
+	if _, err := setupCommand(fs, args, defaultConfig()); err != nil {
+		return setupExitCode(err)
+	}
+	if *from <= 0 || *to < *from {
+		fmt.Fprintln(os.Stderr, "backfill: -from and -to are required, 1 <= from <= to")
+		return 2
+	}
+	height, err := getBlockNumber()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backfill: query chain height:", err)
+		return 1
+	}
+	if *to > height {
+		fmt.Fprintf(os.Stderr, "backfill: -to %d is above chain height %d\n", *to, height)
+		return 2
+	}
+
+	s := initDB()
+	blocks := newSQLBlockRepo(s)
+	synced, skipped := 0, 0
+	failed := make([]int, 0)
+	for n := *from; n <= *to; n++ {
+		if _, err := blocks.Get(n); err == nil {
+			skipped++
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			logger.Error("查询区块失败", logKeyBlockNum, n, "error", err)
+			return 1
+		}
+		s.synBlockTask(n)
+		// synBlockTask 在节点请求失败时只记录日志，这里按是否入库判断结果
+		if _, err := blocks.Get(n); err != nil {
+			failed = append(failed, n)
+			continue
+		}
+		synced++
+	}
+	addressTasks.Wait()
+
+	logger.Info("补同步完成", "from", *from, "to", *to, "synced", synced, "skipped", skipped, "failed", len(failed))
+	fmt.Printf("backfill %d-%d: synced %d, skipped %d (already indexed), failed %d\n", *from, *to, synced, skipped, len(failed))
+	if len(failed) > 0 {
+		fmt.Printf("failed blocks: %v\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// runVerify 检查节点能否连接，并对比 [from, to] 内本地索引与节点的区块：缺失的区块、区块哈希和交易数。
+// 只读，不执行建表；发现问题时返回 1
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	from := fs.Int("from", 0, "起始区块（含），默认为结束区块之前 100 个区块")
+	to := fs.Int("to", 0, "结束区块（含），默认为本地最新区块")
+	if _, err := setupCommand(fs, args, defaultConfig()); err != nil {
+		return setupExitCode(err)
+	}
+
+	s := NewSQL()
+	srv := NewServer(s)
+	localHeight, err := srv.blocks.MaxBlockNum()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "verify: query index (run migrate first?):", err)
+		return 1
+	}
+	chainHeight, err := getBlockNumber()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "verify: query node:", err)
+		return 1
+	}
+	fmt.Printf("chain height %d, local height %d, behind %d\n", chainHeight, localHeight, chainHeight-localHeight)
+
+	if *to <= 0 {
+		*to = localHeight
+	}
+	if *to > chainHeight {
+		*to = chainHeight
+	}
+	if *from <= 0 {
+		*from = *to - verifyDefaultBlocks + 1
+	}
+	if *from < 1 {
+		*from = 1
+	}
+	if *to < *from {
+		fmt.Println("no blocks to verify")
+		return 0
+	}
+
+	numbers := make([]int, 0, *to-*from+1)
+	for n := *from; n <= *to; n++ {
+		numbers = append(numbers, n)
+	}
+	problems := 0
+	missing := make([]int, 0)
+	for start := 0; start < len(numbers); start += verifyBatchSize {
+		end := start + verifyBatchSize
+		if end > len(numbers) {
+			end = len(numbers)
+		}
+		batch := numbers[start:end]
+		local, err := srv.blocks.GetMany(batch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "verify: query index:", err)
+			return 1
+		}
+		transactions, err := srv.txs.ListByBlocks(batch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "verify: query index:", err)
+			return 1
+		}
+		for _, n := range batch {
+			b, ok := local[n]
+			if !ok {
+				missing = append(missing, n)
+				continue
+			}
+			node, err := getBlockByNumber(n, false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "verify: query block %d from node: %v\n", n, err)
+				return 1
+			}
+			if node == nil {
+				problems++
+				fmt.Printf("block %d: not found on node\n", n)
+				continue
+			}
+			if !strings.EqualFold(b.Hash, node.Hash) {
+				problems++
+				fmt.Printf("block %d: hash mismatch, local %s, node %s\n", n, b.Hash, node.Hash)
+			}
+			if len(transactions[n]) != len(node.Transactions) {
+				problems++
+				fmt.Printf("block %d: transaction count mismatch, local %d, node %d\n", n, len(transactions[n]), len(node.Transactions))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		problems += len(missing)
+		fmt.Printf("missing %d blocks: %s (run backfill)\n", len(missing), blockRanges(missing))
+	}
+	fmt.Printf("verified blocks %d-%d: %d problems\n", *from, *to, problems)
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// blockRanges 将升序的区块号合并为区间，例如 3-5,9
+func blockRanges(numbers []int) string {
+	parts := make([]string, 0)
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(numbers[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// runAccount 处理 account refresh <address>：地址不在 bc_block_account 中时先添加，再从链上刷新并输出结果
+func runAccount(args []string) int {
+	if len(args) == 0 || args[0] != "refresh" {
+		fmt.Fprintln(os.Stderr, "usage: bc_server account refresh [flags] <address>")
+		return 2
+	}
+	rest, err := setupCommand(flag.NewFlagSet("account refresh", flag.ContinueOnError), args[1:], defaultConfig())
+	if err != nil {
+		return setupExitCode(err)
+	}
+	if len(rest) != 1 || !isValidAddress(rest[0]) {
+		fmt.Fprintln(os.Stderr, "usage: bc_server account refresh [flags] <address>, address must be 0x followed by 40 hex characters")
+		return 2
+	}
+	address := strings.ToLower(rest[0])
+
+	s := initDB()
+	s.synAddAddress(address)
+	if err := s.synUpdateAccount(address, 0); err != nil {
+		fmt.Fprintln(os.Stderr, "account refresh:", err)
+		return 1
+	}
+	// 刷新成功后才清除 dirty，失败时留给帐户同步任务重试
+	s.clearAccountDirty(address)
+	account, err := newSQLAccountRepo(s).Get(address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "account refresh:", err)
+		return 1
+	}
+	fmt.Printf("%s balance=%d cred=%d share_num=%d\n", account.Address, account.Balance, account.Cred, account.ShareNnum)
+	return 0
+}
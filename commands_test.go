@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestBlockRanges(t *testing.T) {
+	tests := []struct {
+		numbers []int
+		want    string
+	}{
+		{nil, ""},
+		{[]int{7}, "7"},
+		{[]int{3, 4, 5}, "3-5"},
+		{[]int{3, 4, 5, 9}, "3-5,9"},
+		{[]int{1, 3, 5}, "1,3,5"},
+		{[]int{1, 2, 4, 5, 6, 8, 10, 11}, "1-2,4-6,8,10-11"},
+	}
+	for _, tt := range tests {
+		if got := blockRanges(tt.numbers); got != tt.want {
+			t.Errorf("blockRanges(%v) = %q, want %q", tt.numbers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 服务配置。每个字段可以来自配置文件（YAML 或 TOML，键名见 yaml/toml 标签）、
+// 环境变量（env 标签，.env 中的值不覆盖已有的环境变量）和命令行参数（env 标签转为小写并把 _ 换成 -，
+// 例如 -rpc-url），优先级从低到高为：默认值、配置文件、环境变量、命令行参数。
+// secret 标签的字段在 config print 中隐藏
+type Config struct {
+	Port     string `yaml:"port" toml:"port" env:"PORT" desc:"HTTP 端口"`
+	GRPCPort string `yaml:"grpc_port" toml:"grpc_port" env:"GRPC_PORT" desc:"gRPC 端口，为空时不启动"`
+
+	RPC         RPCConfig         `yaml:"rpc" toml:"rpc"`
+	Contract    ContractConfig    `yaml:"contract" toml:"contract"`
+	DB          DBConfig          `yaml:"db" toml:"db"`
+	Tasks       TasksConfig       `yaml:"tasks" toml:"tasks"`
+	Register    RegisterConfig    `yaml:"register" toml:"register"`
+	Leaderboard LeaderboardConfig `yaml:"leaderboard" toml:"leaderboard"`
+	Stats       StatsConfig       `yaml:"stats" toml:"stats"`
+	GraphQL     GraphQLConfig     `yaml:"graphql" toml:"graphql"`
+	Log         LogConfig         `yaml:"log" toml:"log"`
+
+	AdminToken string `yaml:"admin_token" toml:"admin_token" env:"ADMIN_TOKEN" secret:"true" desc:"管理接口的 Bearer token，为空时禁用管理接口"`
+}
+
+type RPCConfig struct {
+	URL            string `yaml:"url" toml:"url" env:"RPC_URL" desc:"FISCO BCOS 节点 JSON-RPC 地址"`
+	HistoricalCall Switch `yaml:"historical_call" toml:"historical_call" env:"RPC_HISTORICAL_CALL" desc:"节点是否支持按区块高度 call（on/off）"`
+}
+
+type ContractConfig struct {
+	Address         string `yaml:"address" toml:"address" env:"CONTRACT_ADDRESS" desc:"Cred 合约地址"`
+	ABI             string `yaml:"abi" toml:"abi" env:"CONTRACT_ABI" desc:"Cred 合约 ABI（JSON）"`
+	MethodShareData string `yaml:"method_share_data" toml:"method_share_data" env:"CONTRACT_METHOD_SHARADATE" desc:"shareData 方法的 method_id"`
+	MethodHasRole   string `yaml:"method_has_role" toml:"method_has_role" env:"CONTRACT_METHOD_HAS_ROLE" desc:"查询角色的方法名"`
+	MethodRevoke    string `yaml:"method_revoke" toml:"method_revoke" env:"CONTRACT_METHOD_REVOKE" desc:"撤销角色的方法名"`
+}
+
+type DBConfig struct {
+	Driver                 string `yaml:"driver" toml:"driver" env:"DB_DRIVER" desc:"mysql、sqlite 或 postgres"`
+	Host                   string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port                   string `yaml:"port" toml:"port" env:"DB_PORT"`
+	Database               string `yaml:"database" toml:"database" env:"DB_DATABASE"`
+	Username               string `yaml:"username" toml:"username" env:"DB_USERNAME"`
+	Password               string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Path                   string `yaml:"path" toml:"path" env:"DB_PATH" desc:"sqlite 数据库文件"`
+	SSLMode                string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE" desc:"postgres sslmode"`
+	Prefix                 string `yaml:"prefix" toml:"prefix" env:"DB_PREFIX" desc:"表名前缀"`
+	MaxOpenConns           int    `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns           int    `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetimeMinutes int    `yaml:"conn_max_lifetime_minutes" toml:"conn_max_lifetime_minutes" env:"DB_CONN_MAX_LIFETIME_MINUTES"`
+}
+
+// TasksConfig 后台任务开关
+type TasksConfig struct {
+	Pull                   Switch `yaml:"pull" toml:"pull" env:"PULL_TASK_STATUS" desc:"区块同步任务（on/off）"`
+	Account                Switch `yaml:"account" toml:"account" env:"ACCOUNT_TASK_STATUS" desc:"帐户同步任务（on/off）"`
+	AccountFullSyncMinutes int    `yaml:"account_full_sync_minutes" toml:"account_full_sync_minutes" env:"ACCOUNT_FULL_SYNC_MINUTES" desc:"帐户全量校准间隔（分钟）"`
+}
+
+type RegisterConfig struct {
+	Workers          int    `yaml:"workers" toml:"workers" env:"REGISTER_WORKERS" desc:"注册任务 worker 数"`
+	JobMaxAttempts   int    `yaml:"job_max_attempts" toml:"job_max_attempts" env:"REGISTER_JOB_MAX_ATTEMPTS"`
+	BatchConcurrency int    `yaml:"batch_concurrency" toml:"batch_concurrency" env:"REGISTER_BATCH_CONCURRENCY"`
+	WebhookSecret    string `yaml:"webhook_secret" toml:"webhook_secret" env:"REGISTER_WEBHOOK_SECRET" secret:"true" desc:"回调请求的 HMAC-SHA256 签名密钥，为空时不接受 callback_url"`
+}
+
+type LeaderboardConfig struct {
+	ReloadMinutes int `yaml:"reload_minutes" toml:"reload_minutes" env:"LEADERBOARD_RELOAD_MINUTES"`
+}
+
+type StatsConfig struct {
+	RefreshMinutes int `yaml:"refresh_minutes" toml:"refresh_minutes" env:"STATS_REFRESH_MINUTES"`
+	Days           int `yaml:"days" toml:"days" env:"STATS_DAYS"`
+}
+
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+}
+
+type LogConfig struct {
+	Level             string `yaml:"level" toml:"level" env:"LOG_LEVEL" desc:"debug、info、warn 或 error"`
+	Format            string `yaml:"format" toml:"format" env:"LOG_FORMAT" desc:"json 或 logfmt"`
+	File              string `yaml:"file" toml:"file" env:"LOG_FILE"`
+	MaxSizeMB         int    `yaml:"max_size_mb" toml:"max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	MaxBackups        int    `yaml:"max_backups" toml:"max_backups" env:"LOG_MAX_BACKUPS"`
+	MaxAgeDays        int    `yaml:"max_age_days" toml:"max_age_days" env:"LOG_MAX_AGE_DAYS"`
+	RegisterAuditFile string `yaml:"register_audit_file" toml:"register_audit_file" env:"REGISTER_AUDIT_FILE"`
+}
+
+// Switch 开关配置，接受 on/off、true/false、1/0，输出为 on/off
+type Switch bool
+
+func (s *Switch) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "on", "true", "1", "yes":
+		*s = true
+	case "off", "false", "0", "no", "":
+		*s = false
+	default:
+		return fmt.Errorf("invalid switch %q, expected on or off", text)
+	}
+	return nil
+}
+
+func (s Switch) MarshalText() ([]byte, error) {
+	if s {
+		return []byte("on"), nil
+	}
+	return []byte("off"), nil
+}
+
+// cfg 当前生效的配置，main 中加载并校验
+var cfg = defaultConfig()
+
+func defaultConfig() *Config {
+	return &Config{
+		Port: "5924",
+		Contract: ContractConfig{
+			MethodHasRole: "hasRole",
+			MethodRevoke:  "revoke",
+		},
+		DB: DBConfig{
+			Driver:                 "mysql",
+			Path:                   "bc.db",
+			MaxOpenConns:           20,
+			MaxIdleConns:           10,
+			ConnMaxLifetimeMinutes: 30,
+		},
+		Tasks:       TasksConfig{AccountFullSyncMinutes: 60},
+		Register:    RegisterConfig{Workers: 2, JobMaxAttempts: 5, BatchConcurrency: 4},
+		Leaderboard: LeaderboardConfig{ReloadMinutes: 5},
+		Stats:       StatsConfig{RefreshMinutes: 10, Days: 30},
+		GraphQL:     GraphQLConfig{MaxDepth: 8, MaxComplexity: 5000},
+		Log: LogConfig{
+			Level:             "info",
+			Format:            "json",
+			File:              "bc_server.log",
+			MaxSizeMB:         100,
+			MaxBackups:        10,
+			MaxAgeDays:        30,
+			RegisterAuditFile: "register_server.log",
+		},
+	}
+}
+
+// configField 一个可配置的叶子字段
+type configField struct {
+	env    string
+	path   string // 配置文件中的键，例如 db.max_open_conns
+	desc   string
+	secret bool
+	value  reflect.Value
+}
+
+// flagName 命令行参数名，例如 RPC_URL 对应 -rpc-url
+func (f configField) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.env), "_", "-")
+}
+
+func (f configField) set(s string) error {
+	if u, ok := f.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		f.value.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported config type %s", f.value.Type())
+	}
+	return nil
+}
+
+// fields 按定义顺序返回所有带 env 标签的字段
+func (c *Config) fields() []configField {
+	var fields []configField
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			path := prefix + sf.Tag.Get("yaml")
+			if env := sf.Tag.Get("env"); env != "" {
+				fields = append(fields, configField{
+					env:    env,
+					path:   path,
+					desc:   sf.Tag.Get("desc"),
+					secret: sf.Tag.Get("secret") == "true",
+					value:  v.Field(i),
+				})
+			} else if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+".")
+			}
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return fields
+}
+
+// loadConfigFile 按扩展名读取 YAML（.yaml、.yml）或 TOML（.toml）配置文件，未出现的键保持原值
+func (c *Config) loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && err != io.EOF {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// loadEnv 读取 .env（不存在时跳过）后用环境变量覆盖配置
+func (c *Config) loadEnv() error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("load .env: %w", err)
+	}
+	var errs []error
+	for _, f := range c.fields() {
+		// 空值按未设置处理，与 .env.example 中留空的配置项含义一致
+		v := os.Getenv(f.env)
+		if v == "" {
+			continue
+		}
+		if err := f.set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configFlags 注册 -config 和每个配置字段对应的命令行参数。参数在 Parse 时先记录下来，
+// 读取配置文件和环境变量之后再由 apply 写入，保证命令行参数优先
+type configFlags struct {
+	file   string
+	values map[string]string
+	order  []string
+}
+
+func newConfigFlags(fs *flag.FlagSet, c *Config) *configFlags {
+	cf := &configFlags{values: make(map[string]string)}
+	fs.StringVar(&cf.file, "config", os.Getenv("CONFIG_FILE"), "配置文件（.yaml、.yml 或 .toml），也可以用 CONFIG_FILE 指定")
+	for _, f := range c.fields() {
+		name := f.flagName()
+		usage := f.env
+		if f.desc != "" {
+			usage += "：" + f.desc
+		}
+		fs.Func(name, usage, func(s string) error {
+			if _, ok := cf.values[name]; !ok {
+				cf.order = append(cf.order, name)
+			}
+			cf.values[name] = s
+			return nil
+		})
+	}
+	return cf
+}
+
+func (cf *configFlags) apply(c *Config) error {
+	fields := make(map[string]configField)
+	for _, f := range c.fields() {
+		fields[f.flagName()] = f
+	}
+	var errs []error
+	for _, name := range cf.order {
+		if err := fields[name].set(cf.values[name]); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadConfig 在 base（通常为 defaultConfig()）的基础上按优先级合并配置文件、环境变量和命令行参数，
+// 返回未解析的位置参数
+func loadConfig(fs *flag.FlagSet, args []string, base *Config) (*Config, []string, error) {
+	c := base
+	flags := newConfigFlags(fs, c)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if flags.file != "" {
+		if err := c.loadConfigFile(flags.file); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := c.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+	if err := flags.apply(c); err != nil {
+		return nil, nil, err
+	}
+	return c, fs.Args(), nil
+}
+
+// validate 检查必填项和格式，一次返回所有问题
+func (c *Config) validate() error {
+	var errs []string
+	fail := func(env string, format string, args ...interface{}) {
+		for _, f := range c.fields() {
+			if f.env == env {
+				errs = append(errs, fmt.Sprintf("%s (%s, -%s): %s", env, f.path, f.flagName(), fmt.Sprintf(format, args...)))
+				return
+			}
+		}
+	}
+	checkPort := func(env string, port string, required bool) {
+		if port == "" {
+			if required {
+				fail(env, "is required")
+			}
+			return
+		}
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			fail(env, "invalid port %q", port)
+		}
+	}
+
+	checkPort("PORT", c.Port, true)
+	checkPort("GRPC_PORT", c.GRPCPort, false)
+	if c.GRPCPort != "" && c.GRPCPort == c.Port {
+		fail("GRPC_PORT", "must differ from PORT")
+	}
+
+	if c.RPC.URL == "" {
+		fail("RPC_URL", "is required")
+	} else if u, err := url.Parse(c.RPC.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("RPC_URL", "must be an http(s) URL, got %q", c.RPC.URL)
+	}
+
+	if c.Contract.Address == "" {
+		fail("CONTRACT_ADDRESS", "is required")
+	} else if !isValidAddress(c.Contract.Address) {
+		fail("CONTRACT_ADDRESS", "must be 0x followed by 40 hex characters, got %q", c.Contract.Address)
+	}
+	if c.Contract.ABI == "" {
+		fail("CONTRACT_ABI", "is required")
+	} else if _, err := abi.JSON(strings.NewReader(c.Contract.ABI)); err != nil {
+		fail("CONTRACT_ABI", "invalid ABI JSON: %v", err)
+	}
+	if c.Contract.MethodShareData != "" && !methodIdPattern.MatchString(c.Contract.MethodShareData) {
+		fail("CONTRACT_METHOD_SHARADATE", "must be a 4-byte method id like 7bd87591, got %q", c.Contract.MethodShareData)
+	}
+
+	if _, err := newStorage(c.DB.Driver); err != nil {
+		fail("DB_DRIVER", "unsupported driver %q, expected mysql, sqlite or postgres", c.DB.Driver)
+	}
+	if c.DB.Driver == "sqlite" && c.DB.Path == "" {
+		fail("DB_PATH", "is required for sqlite")
+	}
+
+	if _, err := parseLogLevel(c.Log.Level); err != nil {
+		fail("LOG_LEVEL", "%v", err)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "logfmt":
+	default:
+		fail("LOG_FORMAT", "must be json or logfmt, got %q", c.Log.Format)
+	}
+
+	for _, f := range c.fields() {
+		if f.value.Kind() == reflect.Int && f.value.Int() <= 0 {
+			fail(f.env, "must be a positive integer, got %d", f.value.Int())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(errs, "\n  - "))
+}
+
+// apply 将配置写入各模块使用的全局变量
+func (c *Config) apply() {
+	cfg = c
+	port = c.Port
+	rpcUrl = c.RPC.URL
+	rpcHistoricalCall = bool(c.RPC.HistoricalCall)
+	contractAddress = c.Contract.Address
+	abiStr = c.Contract.ABI
+	contractMethodId = strings.ToLower(strings.TrimPrefix(c.Contract.MethodShareData, "0x"))
+	roleQueryMethod = c.Contract.MethodHasRole
+	roleRevokeMethod = c.Contract.MethodRevoke
+	dbDriver = c.DB.Driver
+	dbHost = c.DB.Host
+	dbPort = c.DB.Port
+	dbase = c.DB.Database
+	dbUsername = c.DB.Username
+	dbPassword = c.DB.Password
+	dbPath = c.DB.Path
+	dbSSLMode = c.DB.SSLMode
+	dbTablePrefix = c.DB.Prefix
+	pullTaskStatus = bool(c.Tasks.Pull)
+	accountTaskStatus = bool(c.Tasks.Account)
+	adminToken = c.AdminToken
+}
+
+// redacted 返回隐藏了密码、token 和 RPC_URL 中密码的副本
+func (c *Config) redacted() *Config {
+	out := *c
+	for _, f := range out.fields() {
+		if f.secret && f.value.String() != "" {
+			f.value.SetString("******")
+		}
+	}
+	if u, err := url.Parse(out.RPC.URL); err == nil {
+		out.RPC.URL = u.Redacted()
+	}
+	return &out
+}
+
+// print 按 format（yaml、toml、env）输出配置
+func (c *Config) print(w io.Writer, format string) error {
+	switch format {
+	case "", "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	case "env":
+		for _, f := range c.fields() {
+			v := fmt.Sprint(f.value.Interface())
+			if m, ok := f.value.Interface().(encoding.TextMarshaler); ok {
+				text, _ := m.MarshalText()
+				v = string(text)
+			}
+			if strings.ContainsAny(v, " \n\"'#") {
+				v = strconv.Quote(v)
+			}
+			fmt.Fprintf(w, "%s=%s\n", f.env, v)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q, expected yaml, toml or env", format)
+}
+
+// runConfigCommand 处理 config 子命令：config print [-format yaml|toml|env] [配置参数]，
+// 输出合并后的配置（隐藏敏感字段），配置有误时同时在标准错误输出校验结果
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: bc_server config print [-format yaml|toml|env] [flags]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	format := fs.String("format", "yaml", "输出格式：yaml、toml 或 env")
+	c, _, err := loadConfig(fs, args[1:], defaultConfig())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := c.redacted().print(os.Stdout, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := c.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
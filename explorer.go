@@ -0,0 +1,392 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var hashPattern = regexp.MustCompile("^0x[0-9a-fA-F]{64}$")
+
+func isValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// TransactionDetail /tx/{hash} 的返回数据，source 为 index 表示来自本地索引，node 表示索引中没有、从节点读取
+type TransactionDetail struct {
+	Hash           string          `json:"trans_hash"`
+	BlockNumber    int             `json:"block_num"`
+	BlockHash      string          `json:"block_hash"`
+	BlockTimestamp int64           `json:"block_timestamp,omitempty"`
+	From           string          `json:"from"`
+	To             string          `json:"to"`
+	Input          string          `json:"input"`
+	MethodId       string          `json:"method_id,omitempty"`
+	Method         string          `json:"method,omitempty"`
+	DecodeInput    json.RawMessage `json:"decode_input,omitempty"`
+	Output         string          `json:"output"`
+	DecodeOutput   json.RawMessage `json:"decode_output,omitempty"`
+	Status         int             `json:"status"`
+	GasUsed        string          `json:"gas_used"`
+	ImportTime     int             `json:"import_time"`
+	Logs           []DecodedLog    `json:"logs"`
+	Source         string          `json:"source"`
+}
+
+// DecodedLog 回执中的事件，合约事件按 ABI 解码出事件名和参数
+type DecodedLog struct {
+	Address string                 `json:"address"`
+	Topics  []string               `json:"topics"`
+	Data    string                 `json:"data"`
+	Event   string                 `json:"event,omitempty"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+}
+
+// BlockTransaction 区块详情中的交易摘要，status 为 -1 表示回执尚未同步
+type BlockTransaction struct {
+	Hash       string `json:"trans_hash"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Input      string `json:"-"`
+	MethodId   string `json:"method_id,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Status     int    `json:"status"`
+	GasUsed    string `json:"gas_used"`
+	ImportTime int    `json:"import_time"`
+}
+
+// BlockDetail /block/{numberOrHash} 的返回数据。本地索引只保存高度和哈希，
+// 其余区块头字段从节点读取，节点不可用时为空
+type BlockDetail struct {
+	Number       int                `json:"block_num"`
+	Hash         string             `json:"block_hash"`
+	ParentHash   string             `json:"parent_hash,omitempty"`
+	Timestamp    int64              `json:"timestamp,omitempty"`
+	Sealer       string             `json:"sealer,omitempty"`
+	GasUsed      string             `json:"gas_used,omitempty"`
+	TxsRoot      string             `json:"txs_root,omitempty"`
+	ReceiptsRoot string             `json:"receipts_root,omitempty"`
+	StateRoot    string             `json:"state_root,omitempty"`
+	Version      int                `json:"version,omitempty"`
+	TxCount      int                `json:"tx_count"`
+	Transactions []BlockTransaction `json:"transactions"`
+	Source       string             `json:"source"`
+}
+
+// setHeader 用节点返回的区块头补全区块详情
+func (b *BlockDetail) setHeader(block *NodeBlock) {
+	b.Number = block.Number
+	b.Hash = block.Hash
+	if len(block.ParentInfo) > 0 {
+		b.ParentHash = block.ParentInfo[0].BlockHash
+	}
+	b.Timestamp = block.Timestamp
+	if block.Sealer >= 0 && block.Sealer < len(block.SealerList) {
+		b.Sealer = block.SealerList[block.Sealer]
+	}
+	b.GasUsed = block.GasUsed
+	b.TxsRoot = block.TxsRoot
+	b.ReceiptsRoot = block.ReceiptsRoot
+	b.StateRoot = block.StateRoot
+	b.Version = block.Version
+}
+
+// contractMethod 按 input 的前 4 字节查找合约方法，不是合约调用或方法不在 ABI 中时返回 nil
+func contractMethod(contractAbi abi.ABI, to string, input string) *abi.Method {
+	if !strings.EqualFold(to, contractAddress) || len(input) < 10 {
+		return nil
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(input, "0x")[:8])
+	if err != nil {
+		return nil
+	}
+	method, err := contractAbi.MethodById(sig)
+	if err != nil {
+		return nil
+	}
+	return method
+}
+
+// decodeTransactionDetail 补全方法名，入库时未解码的 input/output 按 ABI 即时解码
+func decodeTransactionDetail(contractAbi abi.ABI, d *TransactionDetail) {
+	method := contractMethod(contractAbi, d.To, d.Input)
+	if method == nil {
+		return
+	}
+	d.MethodId = hex.EncodeToString(method.ID)
+	d.Method = method.RawName
+	if len(d.DecodeInput) == 0 {
+		data, err := hexutil.Decode(d.Input)
+		if err == nil {
+			if values, err := method.Inputs.Unpack(data[4:]); err == nil {
+				d.DecodeInput, _ = json.Marshal(values)
+			}
+		}
+	}
+	if len(d.DecodeOutput) == 0 && len(d.Output) > 2 {
+		data, err := hexutil.Decode(d.Output)
+		if err == nil {
+			if values, err := method.Outputs.Unpack(data); err == nil {
+				d.DecodeOutput, _ = json.Marshal(values)
+			}
+		}
+	}
+}
+
+// decodeBlockTransactions 补全区块内合约交易的方法名和交易数
+func decodeBlockTransactions(contractAbi abi.ABI, b *BlockDetail) {
+	for i := range b.Transactions {
+		tx := &b.Transactions[i]
+		if method := contractMethod(contractAbi, tx.To, tx.Input); method != nil {
+			tx.MethodId = hex.EncodeToString(method.ID)
+			tx.Method = method.RawName
+		}
+	}
+	b.TxCount = len(b.Transactions)
+}
+
+// decodeLogs 按合约 ABI 解码回执中的事件，非本合约或无法识别的事件只返回原始数据
+func decodeLogs(contractAbi abi.ABI, logs []LogEntry) []DecodedLog {
+	decoded := make([]DecodedLog, 0, len(logs))
+	for _, entry := range logs {
+		d := DecodedLog{Address: entry.Address, Topics: entry.Topics, Data: entry.Data}
+		decoded = append(decoded, d)
+		if !strings.EqualFold(entry.Address, contractAddress) || len(entry.Topics) == 0 {
+			continue
+		}
+		event, err := contractAbi.EventByID(common.HexToHash(entry.Topics[0]))
+		if err != nil {
+			continue
+		}
+		args := make(map[string]interface{})
+		topics := make([]common.Hash, 0, len(entry.Topics)-1)
+		for _, topic := range entry.Topics[1:] {
+			topics = append(topics, common.HexToHash(topic))
+		}
+		indexed := make(abi.Arguments, 0)
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed = append(indexed, input)
+			}
+		}
+		if err := abi.ParseTopicsIntoMap(args, indexed, topics); err != nil {
+			continue
+		}
+		if data, err := hexutil.Decode(entry.Data); err == nil && len(data) > 0 {
+			if err := event.Inputs.UnpackIntoMap(args, data); err != nil {
+				continue
+			}
+		}
+		decoded[len(decoded)-1].Event = event.RawName
+		decoded[len(decoded)-1].Args = args
+	}
+	return decoded
+}
+
+// transactionFromNode 本地索引中没有的交易，从节点读取交易和回执，都不存在时返回 nil
+func transactionFromNode(hash string) (*TransactionDetail, error) {
+	tx, err := getTransactionByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := getTransactionReceipt(hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil && receipt == nil {
+		return nil, nil
+	}
+	d := &TransactionDetail{Hash: hash, Status: -1, Source: "node"}
+	if tx != nil {
+		d.From, d.To, d.Input, d.ImportTime = tx.From, tx.To, tx.Input, tx.ImportTime
+	}
+	if receipt != nil {
+		d.BlockNumber = receipt.BlockNumber
+		if tx == nil {
+			d.From, d.To, d.Input = receipt.From, receipt.To, receipt.Input
+		}
+	}
+	return d, nil
+}
+
+// getTransaction 处理 /tx/{hash}，返回交易的 input/output 及其解码结果、状态、事件和所在区块，
+// 本地尚未索引时从节点读取
+func (srv *Server) getTransaction(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	hash := strings.TrimPrefix(r.URL.Path, "/tx/")
+	if !isValidHash(hash) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidHash, "Invalid transaction hash")
+		return
+	}
+	hash = strings.ToLower(hash)
+	l = l.With(logKeyTxHash, hash)
+
+	d, err := srv.txs.Get(hash)
+	if err == sql.ErrNoRows {
+		d, err = transactionFromNode(hash)
+		if err != nil {
+			l.Error("从节点查询交易失败", "error", err)
+			writeError(w, http.StatusBadGateway, errCodeNode, "Error querying node")
+			return
+		}
+		if d == nil {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "Transaction not found")
+			return
+		}
+	} else if err != nil {
+		l.Error("Error querying database", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		return
+	} else {
+		d.Source = "index"
+	}
+
+	// 事件不入库，从回执读取；回执尚未同步到索引时一并补全状态和 output
+	d.Logs = make([]DecodedLog, 0)
+	receipt, err := getTransactionReceipt(hash)
+	if err != nil {
+		l.Warn("查询交易回执失败", "error", err)
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		l.Error("加载合约失败", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+	if receipt != nil {
+		if d.Status == -1 {
+			d.Status, d.Output, d.GasUsed = receipt.Status, receipt.Output, receipt.GasUsed
+		}
+		d.Logs = decodeLogs(contractAbi, receipt.Logs)
+	}
+	decodeTransactionDetail(contractAbi, d)
+	srv.fillBlockMetadata(l, d)
+
+	response := ResponseList{
+		Data: d,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// fillBlockMetadata 补全交易所在区块的哈希和时间戳，节点不可用时保留已有字段
+func (srv *Server) fillBlockMetadata(l *slog.Logger, d *TransactionDetail) {
+	if d.BlockNumber <= 0 {
+		return
+	}
+	block, err := getBlockByNumber(d.BlockNumber, true)
+	if err != nil {
+		l.Warn("查询区块头失败", logKeyBlockNum, d.BlockNumber, "error", err)
+		return
+	}
+	if block == nil {
+		return
+	}
+	d.BlockHash = block.Hash
+	d.BlockTimestamp = block.Timestamp
+}
+
+// getBlock 处理 /block/{numberOrHash}，按十进制高度或 0x 开头的区块哈希查询区块头和交易列表，
+// 本地尚未索引时从节点读取，此时交易的 status 为 -1
+func (srv *Server) getBlock(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	key := strings.TrimPrefix(r.URL.Path, "/block/")
+	var number int
+	byHash := isValidHash(key)
+	if byHash {
+		key = strings.ToLower(key)
+	} else {
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Invalid block number or hash")
+			return
+		}
+		number = n
+	}
+
+	var b *BlockDetail
+	var err error
+	if byHash {
+		b, err = srv.blocks.GetByHash(key)
+	} else {
+		b, err = srv.blocks.Get(number)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		l.Error("Error querying database", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		return
+	}
+
+	contractAbi, aerr := abi.JSON(strings.NewReader(abiStr))
+	if aerr != nil {
+		l.Error("加载合约失败", "error", aerr)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+
+	if err == nil {
+		b.Source = "index"
+		b.Transactions, err = srv.txs.ListByBlock(b.Number)
+		if err != nil {
+			l.Error("Error querying database", "error", err)
+			writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+			return
+		}
+		header, err := getBlockByNumber(b.Number, true)
+		if err != nil {
+			l.Warn("查询区块头失败", logKeyBlockNum, b.Number, "error", err)
+		} else if header != nil {
+			b.setHeader(header)
+		}
+	} else {
+		var block *NodeBlock
+		if byHash {
+			block, err = getBlockByHash(key, false)
+		} else {
+			block, err = getBlockByNumber(number, false)
+		}
+		if err != nil {
+			l.Error("从节点查询区块失败", "error", err)
+			writeError(w, http.StatusBadGateway, errCodeNode, "Error querying node")
+			return
+		}
+		if block == nil {
+			writeError(w, http.StatusNotFound, errCodeNotFound, "Block not found")
+			return
+		}
+		b = &BlockDetail{Source: "node"}
+		b.setHeader(block)
+		b.Transactions = make([]BlockTransaction, 0, len(block.Transactions))
+		for _, tx := range block.Transactions {
+			b.Transactions = append(b.Transactions, BlockTransaction{
+				Hash:       tx.Hash,
+				From:       tx.From,
+				To:         tx.To,
+				Input:      tx.Input,
+				Status:     -1,
+				ImportTime: tx.ImportTime,
+			})
+		}
+	}
+	decodeBlockTransactions(contractAbi, b)
+
+	response := ResponseList{
+		Data: b,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 每写出多少行刷新一次缓冲，让客户端尽快收到数据
+const exportFlushRows = 1000
+
+// TransactionExport 导出的一行交易数据，decode_input 在 CSV 中按 JSON 字符串输出
+type TransactionExport struct {
+	BlockNumber     int             `json:"block_num"`
+	Hash            string          `json:"trans_hash"`
+	From            string          `json:"from"`
+	To              string          `json:"to"`
+	MethodId        string          `json:"method_id"`
+	Status          int             `json:"status"`
+	GasUsed         string          `json:"gas_used"`
+	ImportTime      int64           `json:"import_time"`
+	DecodeInput     json.RawMessage `json:"decode_input"`
+	HeartRate       string          `json:"heart_rate"`
+	BreathRate      string          `json:"breath_rate"`
+	SleepState      int             `json:"sleep_state"`
+	HeartChange     string          `json:"heart_change"`
+	SleepBreathing  string          `json:"sleep_breathing"`
+	PersonId        int             `json:"person_id"`
+	ContactName     string          `json:"contact_name"`
+	ContactIdentity string          `json:"contact_identity"`
+}
+
+var transactionExportHeader = []string{
+	"block_num", "trans_hash", "from", "to", "method_id", "status", "gas_used", "import_time", "decode_input",
+	"heart_rate", "breath_rate", "sleep_state", "heart_change", "sleep_breathing", "person_id", "contact_name", "contact_identity",
+}
+
+func (tx *TransactionExport) record() []string {
+	return []string{
+		strconv.Itoa(tx.BlockNumber), tx.Hash, tx.From, tx.To, tx.MethodId, strconv.Itoa(tx.Status), tx.GasUsed,
+		strconv.FormatInt(tx.ImportTime, 10), string(tx.DecodeInput),
+		tx.HeartRate, tx.BreathRate, strconv.Itoa(tx.SleepState), tx.HeartChange, tx.SleepBreathing,
+		strconv.Itoa(tx.PersonId), tx.ContactName, tx.ContactIdentity,
+	}
+}
+
+// exportWriter 按格式逐行写出交易
+type exportWriter interface {
+	Write(tx *TransactionExport) error
+	Flush() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(transactionExportHeader); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{w: cw}, nil
+}
+
+func (c *csvExportWriter) Write(tx *TransactionExport) error {
+	return c.w.Write(tx.record())
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonExportWriter) Write(tx *TransactionExport) error {
+	return n.enc.Encode(tx)
+}
+
+func (n *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+// exportTransactions 处理 /export/transactions，按 getTransByAddress 的过滤条件（另支持 person）导出全部交易
+func (srv *Server) exportTransactions(w http.ResponseWriter, r *http.Request) {
+	srv.export(w, r, "transactions", nil)
+}
+
+// exportVitals 处理 /export/vitals，只导出成功的 shareData 交易（体征数据）
+func (srv *Server) exportVitals(w http.ResponseWriter, r *http.Request) {
+	srv.export(w, r, "vitals", func(f *TransactionFilter) {
+		status := 0
+		f.MethodId = contractMethodId
+		f.Status = &status
+	})
+}
+
+// export 从数据库游标逐行写出 CSV（format=csv，默认）或 NDJSON（format=ndjson），不在内存中缓存整个结果；
+// gzip=true 时以 Content-Encoding: gzip 压缩输出。未指定 order 时按区块升序导出。
+// 必须指定帐户、人员或完整的时间范围（start 和 end），不允许导出整张交易表
+func (srv *Server) export(w http.ResponseWriter, r *http.Request, name string, scope func(f *TransactionFilter)) {
+	l := loggerFromContext(r.Context())
+	f, _, err := parseTransactionFilter(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if !f.hasSubject() && (f.Start <= 0 || f.End <= 0) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Missing address, person or start/end")
+		return
+	}
+	queryValues := r.URL.Query()
+	if queryValues.Get("order") == "" {
+		f.Ascending = true
+	}
+	if scope != nil {
+		scope(&f)
+	}
+
+	format := queryValues.Get("format")
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case "", "csv":
+		format = "csv"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	default:
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, fmt.Sprintf("invalid format %q", format))
+		return
+	}
+	filename := name + "-" + time.Now().UTC().Format("20060102150405") + "." + format
+
+	var out io.Writer = w
+	var gz *gzip.Writer
+	w.Header().Set("Content-Type", contentType)
+	if queryValues.Get("gzip") == "true" {
+		w.Header().Set("Content-Encoding", "gzip")
+		filename += ".gz"
+		gz = gzip.NewWriter(w)
+		out = gz
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	var ew exportWriter
+	if format == "csv" {
+		ew, err = newCSVExportWriter(out)
+		if err != nil {
+			l.Error("导出失败", "error", err)
+			return
+		}
+	} else {
+		ew = &ndjsonExportWriter{enc: json.NewEncoder(out)}
+	}
+
+	flusher, _ := w.(http.Flusher)
+	rows := 0
+	err = srv.txs.Export(f, func(tx *TransactionExport) error {
+		if err := ew.Write(tx); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := ew.Flush(); err != nil {
+				return err
+			}
+			if gz != nil {
+				if err := gz.Flush(); err != nil {
+					return err
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = ew.Flush()
+	}
+	if gz != nil && err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		l.Error("导出失败", "export", name, "rows", rows, "error", err)
+		if rows == 0 {
+			// 还没有写出任何数据，可以返回错误状态
+			w.Header().Del("Content-Encoding")
+			w.Header().Del("Content-Disposition")
+			writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		}
+		// 已经开始输出时只能中断，gzip 流不写结尾，客户端可以据此发现数据不完整
+		return
+	}
+	l.Info("导出完成", "export", name, "format", format, "rows", rows)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAdminToken = "test-admin-token"
+
+func withAdminToken(t *testing.T) {
+	t.Helper()
+	saved := adminToken
+	adminToken = testAdminToken
+	t.Cleanup(func() { adminToken = saved })
+}
+
+// serveAdmin 经过路由表请求 target，withToken 为 true 时带上管理员令牌
+func serveAdmin(srv *Server, target string, withToken bool) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if withToken {
+		r.Header.Set("Authorization", "Bearer "+testAdminToken)
+	}
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, r)
+	return w
+}
+
+func TestExportRequiresAdminAndFilter(t *testing.T) {
+	withAdminToken(t)
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	srv := NewServer(s)
+
+	tests := []struct {
+		target    string
+		withToken bool
+		status    int
+		rows      int
+	}{
+		{"/export/transactions?address=" + testAddressA, false, http.StatusUnauthorized, 0},
+		{"/export/vitals?person=7", false, http.StatusUnauthorized, 0},
+		{"/export/transactions", true, http.StatusBadRequest, 0},
+		{"/export/transactions?status=0", true, http.StatusBadRequest, 0},
+		{"/export/transactions?start=1000", true, http.StatusBadRequest, 0},
+		{"/export/transactions?address=" + testAddressA, true, http.StatusOK, 3},
+		{"/export/transactions?start=1000&end=3000", true, http.StatusOK, 2},
+		{"/export/vitals?person=7", true, http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serveAdmin(srv, tt.target, tt.withToken)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			// CSV 第一行为表头
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			if len(lines)-1 != tt.rows {
+				t.Errorf("exported %d rows, want %d:\n%s", len(lines)-1, tt.rows, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	fhirContentType = "application/fhir+json"
+	loincSystem     = "http://loinc.org"
+	ucumSystem      = "http://unitsofmeasure.org"
+	// 睡眠状态没有对应的 LOINC 编码，使用本服务的编码系统
+	fhirLocalSystem       = "urn:bc-go-service:vitals"
+	fhirCategorySystem    = "http://terminology.hl7.org/CodeSystem/observation-category"
+	fhirTxHashSystem      = "urn:fisco-bcos:tx-hash"
+	fhirAddressSystem     = "urn:fisco-bcos:address"
+	fhirProvenanceRoleSrc = "http://terminology.hl7.org/CodeSystem/provenance-participant-type"
+)
+
+type fhirCoding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+type fhirCodeableConcept struct {
+	Coding []fhirCoding `json:"coding"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type fhirIdentifier struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+type fhirReference struct {
+	Reference  string          `json:"reference,omitempty"`
+	Identifier *fhirIdentifier `json:"identifier,omitempty"`
+	Display    string          `json:"display,omitempty"`
+}
+
+type fhirQuantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	System string  `json:"system"`
+	Code   string  `json:"code"`
+}
+
+// FHIRObservation FHIR R4 Observation，只包含体征导出用到的字段
+type FHIRObservation struct {
+	ResourceType      string                `json:"resourceType"`
+	Id                string                `json:"id"`
+	Identifier        []fhirIdentifier      `json:"identifier"`
+	Status            string                `json:"status"`
+	Category          []fhirCodeableConcept `json:"category"`
+	Code              fhirCodeableConcept   `json:"code"`
+	Subject           fhirReference         `json:"subject"`
+	EffectiveDateTime string                `json:"effectiveDateTime"`
+	ValueQuantity     *fhirQuantity         `json:"valueQuantity,omitempty"`
+	ValueInteger      *int                  `json:"valueInteger,omitempty"`
+	ValueString       string                `json:"valueString,omitempty"`
+}
+
+type fhirProvenanceAgent struct {
+	Type fhirCodeableConcept `json:"type"`
+	Who  fhirReference       `json:"who"`
+}
+
+type fhirProvenanceEntity struct {
+	Role string        `json:"role"`
+	What fhirReference `json:"what"`
+}
+
+// FHIRProvenance FHIR R4 Provenance，记录 Observation 来自哪一笔链上交易
+type FHIRProvenance struct {
+	ResourceType string                 `json:"resourceType"`
+	Id           string                 `json:"id"`
+	Target       []fhirReference        `json:"target"`
+	Recorded     string                 `json:"recorded"`
+	Agent        []fhirProvenanceAgent  `json:"agent"`
+	Entity       []fhirProvenanceEntity `json:"entity"`
+}
+
+type fhirBundleEntry struct {
+	FullUrl  string          `json:"fullUrl"`
+	Resource interface{}     `json:"resource"`
+	Search   fhirEntrySearch `json:"search"`
+}
+
+// fhirEntrySearch searchset 中 Observation 为 match，关联的 Provenance 为 include
+type fhirEntrySearch struct {
+	Mode string `json:"mode"`
+}
+
+// fhirUUID 由交易哈希和资源类型生成固定的 UUID（按 RFC 4122 第 5 版格式），同一笔交易重复导出时 id 不变
+func fhirUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func fhirCategory(code string, display string) []fhirCodeableConcept {
+	return []fhirCodeableConcept{{Coding: []fhirCoding{{System: fhirCategorySystem, Code: code, Display: display}}}}
+}
+
+// vitalsObservations 将一笔 shareData 交易的心率、呼吸频率和睡眠状态转换为 Observation，
+// 心率、呼吸频率无法解析为数字时按 valueString 输出，为空时跳过
+func vitalsObservations(tx *TransactionExport) []*FHIRObservation {
+	base := func(kind string) *FHIRObservation {
+		return &FHIRObservation{
+			ResourceType:      "Observation",
+			Id:                fhirUUID(tx.Hash + "/" + kind),
+			Identifier:        []fhirIdentifier{{System: fhirTxHashSystem, Value: tx.Hash}},
+			Status:            "final",
+			Category:          fhirCategory("vital-signs", "Vital Signs"),
+			Subject:           fhirReference{Reference: "Patient/" + strconv.Itoa(tx.PersonId), Display: tx.ContactName},
+			EffectiveDateTime: time.UnixMilli(tx.ImportTime).UTC().Format(time.RFC3339),
+		}
+	}
+	rate := func(kind string, value string, code string, display string) *FHIRObservation {
+		if value == "" {
+			return nil
+		}
+		o := base(kind)
+		o.Code = fhirCodeableConcept{Coding: []fhirCoding{{System: loincSystem, Code: code, Display: display}}, Text: display}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			o.ValueQuantity = &fhirQuantity{Value: v, Unit: "/min", System: ucumSystem, Code: "/min"}
+		} else {
+			o.ValueString = value
+		}
+		return o
+	}
+
+	observations := make([]*FHIRObservation, 0, 3)
+	if o := rate("heart-rate", tx.HeartRate, "8867-4", "Heart rate"); o != nil {
+		observations = append(observations, o)
+	}
+	if o := rate("respiratory-rate", tx.BreathRate, "9279-1", "Respiratory rate"); o != nil {
+		observations = append(observations, o)
+	}
+	sleep := base("sleep-state")
+	sleep.Category = fhirCategory("activity", "Activity")
+	sleep.Code = fhirCodeableConcept{Coding: []fhirCoding{{System: fhirLocalSystem, Code: "sleep-state", Display: "Sleep state"}}, Text: "Sleep state"}
+	sleepState := tx.SleepState
+	sleep.ValueInteger = &sleepState
+	observations = append(observations, sleep)
+	return observations
+}
+
+// vitalsProvenance 记录 Observation 来自的链上交易哈希、上链时间和提交地址
+func vitalsProvenance(tx *TransactionExport, observations []*FHIRObservation) *FHIRProvenance {
+	targets := make([]fhirReference, 0, len(observations))
+	for _, o := range observations {
+		targets = append(targets, fhirReference{Reference: "urn:uuid:" + o.Id})
+	}
+	return &FHIRProvenance{
+		ResourceType: "Provenance",
+		Id:           fhirUUID(tx.Hash + "/provenance"),
+		Target:       targets,
+		Recorded:     time.UnixMilli(tx.ImportTime).UTC().Format(time.RFC3339),
+		Agent: []fhirProvenanceAgent{{
+			Type: fhirCodeableConcept{Coding: []fhirCoding{{System: fhirProvenanceRoleSrc, Code: "author", Display: "Author"}}},
+			Who:  fhirReference{Identifier: &fhirIdentifier{System: fhirAddressSystem, Value: tx.From}},
+		}},
+		Entity: []fhirProvenanceEntity{{
+			Role: "source",
+			What: fhirReference{
+				Identifier: &fhirIdentifier{System: fhirTxHashSystem, Value: tx.Hash},
+				Display:    "block " + strconv.Itoa(tx.BlockNumber),
+			},
+		}},
+	}
+}
+
+// exportFHIRObservations 处理 /fhir/Observation?person=&start=&end=，将人员在时间范围内成功的 shareData 交易
+// 导出为 FHIR R4 searchset Bundle：每笔交易对应心率、呼吸频率、睡眠状态 Observation 和一条指向交易哈希的 Provenance。
+// 必须指定 person 和完整的时间范围（start、end）。Bundle 从数据库游标逐条写出，不在内存中缓存
+func (srv *Server) exportFHIRObservations(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	f, _, err := parseTransactionFilter(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if f.PersonId <= 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Missing person")
+		return
+	}
+	if f.Start <= 0 || f.End <= 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Missing start or end")
+		return
+	}
+	status := 0
+	f.MethodId = contractMethodId
+	f.Status = &status
+	f.Ascending = true
+
+	w.Header().Set("Content-Type", fhirContentType)
+	entries := 0
+	// Bundle 头在第一条数据前写出，查询失败且尚未写出数据时仍可以返回错误状态
+	writeHeader := func() error {
+		_, err := fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","timestamp":%q,"entry":[`, time.Now().UTC().Format(time.RFC3339))
+		return err
+	}
+	writeEntry := func(fullUrl string, resource interface{}, mode string) error {
+		data, err := json.Marshal(fhirBundleEntry{FullUrl: fullUrl, Resource: resource, Search: fhirEntrySearch{Mode: mode}})
+		if err != nil {
+			return err
+		}
+		if entries == 0 {
+			err = writeHeader()
+		} else {
+			_, err = io.WriteString(w, ",")
+		}
+		if err != nil {
+			return err
+		}
+		entries++
+		_, err = w.Write(data)
+		return err
+	}
+
+	err = srv.txs.Export(f, func(tx *TransactionExport) error {
+		observations := vitalsObservations(tx)
+		for _, o := range observations {
+			if err := writeEntry("urn:uuid:"+o.Id, o, "match"); err != nil {
+				return err
+			}
+		}
+		p := vitalsProvenance(tx, observations)
+		return writeEntry("urn:uuid:"+p.Id, p, "include")
+	})
+	if err != nil {
+		l.Error("导出 FHIR 失败", "entries", entries, "error", err)
+		if entries == 0 {
+			writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		}
+		return
+	}
+	if entries == 0 {
+		writeHeader()
+	}
+	io.WriteString(w, "]}\n")
+	l.Info("导出 FHIR 完成", "person_id", f.PersonId, "entries", entries)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFHIRObservationsRequiresAdminAndFilter(t *testing.T) {
+	withAdminToken(t)
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	srv := NewServer(s)
+
+	tests := []struct {
+		target    string
+		withToken bool
+		status    int
+	}{
+		{"/fhir/Observation?person=7&start=1&end=9000", false, http.StatusUnauthorized},
+		{"/fhir/Observation?start=1&end=9000", true, http.StatusBadRequest},
+		{"/fhir/Observation?person=7", true, http.StatusBadRequest},
+		{"/fhir/Observation?person=7&start=1", true, http.StatusBadRequest},
+		{"/fhir/Observation?person=7&start=1&end=9000", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serveAdmin(srv, tt.target, tt.withToken)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), `"Patient/7"`) {
+				t.Errorf("bundle without subject: %s", w.Body.String())
+			}
+		})
+	}
+}
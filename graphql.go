@@ -0,0 +1,609 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	// graphqlBatchSize 每次批量查询的最大 key 数，与 registeredAddresses 的分批大小一致
+	graphqlBatchSize = 200
+	// graphqlNodeConcurrency 批量读取回执时对节点的最大并发请求数
+	graphqlNodeConcurrency = 8
+	// graphqlListEstimate 没有 first 参数的列表字段（如区块内的交易）计算复杂度时按该长度估算
+	graphqlListEstimate    = 10
+	graphqlMaxRequestBytes = 1 << 20
+)
+
+var errGraphQLDatabase = errors.New("Error querying database")
+
+var graphqlSchema graphql.Schema
+
+func init() {
+	var err error
+	graphqlSchema, err = newGraphQLSchema()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// loadResult 批量查询中一个 key 的结果，found 为 false 表示 key 不存在
+type loadResult[V any] struct {
+	value V
+	found bool
+	err   error
+}
+
+// batchLoader 在一次请求内合并同一层字段的查询。resolver 调用 load 登记 key 并返回 thunk，
+// graphql-go 按广度优先执行 thunk，同一层的字段全部登记之后才会执行第一个 thunk，
+// 此时把所有未查询的 key 合并为一次（超过 graphqlBatchSize 时分批）查询，结果在请求内缓存
+type batchLoader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	results map[K]loadResult[V]
+	queued  map[K]bool
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:   fetch,
+		results: make(map[K]loadResult[V]),
+		queued:  make(map[K]bool),
+	}
+}
+
+func (l *batchLoader[K, V]) load(key K) func() (V, bool, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.results[key]; !ok {
+			l.flush()
+		}
+		r := l.results[key]
+		return r.value, r.found, r.err
+	}
+}
+
+func (l *batchLoader[K, V]) flush() {
+	keys := l.pending
+	l.pending = nil
+	for start := 0; start < len(keys); start += graphqlBatchSize {
+		end := start + graphqlBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+		values, err := l.fetch(chunk)
+		for _, key := range chunk {
+			value, found := values[key]
+			l.results[key] = loadResult[V]{value: value, found: found, err: err}
+			delete(l.queued, key)
+		}
+	}
+}
+
+// valueThunk 单个对象字段的 thunk，key 不存在时返回 null
+func valueThunk[K comparable, V any](l *batchLoader[K, V], key K) func() (interface{}, error) {
+	load := l.load(key)
+	return func() (interface{}, error) {
+		value, found, err := load()
+		if err != nil || !found {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// listThunk 列表字段的 thunk，key 不存在时返回空列表
+func listThunk[K comparable, V any](l *batchLoader[K, []V], key K) func() (interface{}, error) {
+	load := l.load(key)
+	return func() (interface{}, error) {
+		value, _, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			value = make([]V, 0)
+		}
+		return value, nil
+	}
+}
+
+// listLoaderKey 按 first 和是否只查体征区分列表 loader，同一请求内参数相同的字段共用一次查询
+type listLoaderKey struct {
+	vitals bool
+	limit  int
+}
+
+// graphqlLoaders 一次 GraphQL 请求的 loader，随请求创建，查询结果只在请求内缓存
+type graphqlLoaders struct {
+	srv         *Server
+	logger      *slog.Logger
+	contractAbi abi.ABI
+
+	blocks            *batchLoader[int, BlockDetail]
+	blockTransactions *batchLoader[int, []IndexedTransaction]
+	transactions      *batchLoader[string, IndexedTransaction]
+	accounts          *batchLoader[string, AccountResponse]
+	accountPersons    *batchLoader[string, LinkedPerson]
+	persons           *batchLoader[int, LinkedPerson]
+	personAccounts    *batchLoader[int, []AccountResponse]
+	events            *batchLoader[string, []DecodedLog]
+
+	mu        sync.Mutex
+	senderTxs map[listLoaderKey]*batchLoader[string, []IndexedTransaction]
+	personTxs map[listLoaderKey]*batchLoader[int, []IndexedTransaction]
+}
+
+type graphqlCtxKey struct{}
+
+func loadersFrom(p graphql.ResolveParams) *graphqlLoaders {
+	return p.Context.Value(graphqlCtxKey{}).(*graphqlLoaders)
+}
+
+// dbError 记录数据库错误，返回给客户端的错误不包含 SQL 细节
+func (l *graphqlLoaders) dbError(err error) error {
+	l.logger.Error("GraphQL 查询数据库失败", "error", err)
+	return errGraphQLDatabase
+}
+
+func newGraphQLLoaders(srv *Server, logger *slog.Logger, contractAbi abi.ABI) *graphqlLoaders {
+	l := &graphqlLoaders{
+		srv:         srv,
+		logger:      logger,
+		contractAbi: contractAbi,
+		senderTxs:   make(map[listLoaderKey]*batchLoader[string, []IndexedTransaction]),
+		personTxs:   make(map[listLoaderKey]*batchLoader[int, []IndexedTransaction]),
+	}
+	l.blocks = newBatchLoader(func(numbers []int) (map[int]BlockDetail, error) {
+		blocks, err := srv.blocks.GetMany(numbers)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return blocks, nil
+	})
+	l.blockTransactions = newBatchLoader(func(numbers []int) (map[int][]IndexedTransaction, error) {
+		transactions, err := srv.txs.ListByBlocks(numbers)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return transactions, nil
+	})
+	l.transactions = newBatchLoader(func(hashes []string) (map[string]IndexedTransaction, error) {
+		transactions, err := srv.txs.GetMany(hashes)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return transactions, nil
+	})
+	l.accounts = newBatchLoader(func(addresses []string) (map[string]AccountResponse, error) {
+		accounts, err := srv.accounts.GetMany(addresses)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return accounts, nil
+	})
+	l.accountPersons = newBatchLoader(func(addresses []string) (map[string]LinkedPerson, error) {
+		persons, err := srv.txs.PersonsOf(addresses)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return persons, nil
+	})
+	l.persons = newBatchLoader(func(ids []int) (map[int]LinkedPerson, error) {
+		persons, err := srv.txs.Persons(ids)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return persons, nil
+	})
+	// 人员的帐户需要先查地址再查帐户，两次批量查询都在同一个 loader 中完成
+	l.personAccounts = newBatchLoader(func(ids []int) (map[int][]AccountResponse, error) {
+		senders, err := srv.txs.SendersOf(ids)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		addresses := make([]string, 0)
+		for _, list := range senders {
+			addresses = append(addresses, list...)
+		}
+		accounts := make(map[string]AccountResponse, len(addresses))
+		for start := 0; start < len(addresses); start += graphqlBatchSize {
+			end := start + graphqlBatchSize
+			if end > len(addresses) {
+				end = len(addresses)
+			}
+			chunk, err := srv.accounts.GetMany(addresses[start:end])
+			if err != nil {
+				return nil, l.dbError(err)
+			}
+			for address, a := range chunk {
+				accounts[address] = a
+			}
+		}
+		result := make(map[int][]AccountResponse, len(senders))
+		for id, list := range senders {
+			for _, address := range list {
+				if a, ok := accounts[address]; ok {
+					result[id] = append(result[id], a)
+				}
+			}
+		}
+		return result, nil
+	})
+	l.events = newBatchLoader(l.fetchEvents)
+	return l
+}
+
+// senderTransactions 帐户最新交易（vitals 为 true 时只查体征上报）的 loader
+func (l *graphqlLoaders) senderTransactions(vitals bool, limit int) *batchLoader[string, []IndexedTransaction] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := listLoaderKey{vitals: vitals, limit: limit}
+	if loader, ok := l.senderTxs[key]; ok {
+		return loader
+	}
+	loader := newBatchLoader(func(addresses []string) (map[string][]IndexedTransaction, error) {
+		transactions, err := l.srv.txs.ListBySenders(addresses, vitals, limit)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return transactions, nil
+	})
+	l.senderTxs[key] = loader
+	return loader
+}
+
+// personTransactions 人员最新交易（vitals 为 true 时只查体征上报）的 loader
+func (l *graphqlLoaders) personTransactions(vitals bool, limit int) *batchLoader[int, []IndexedTransaction] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := listLoaderKey{vitals: vitals, limit: limit}
+	if loader, ok := l.personTxs[key]; ok {
+		return loader
+	}
+	loader := newBatchLoader(func(ids []int) (map[int][]IndexedTransaction, error) {
+		transactions, err := l.srv.txs.ListByPersons(ids, vitals, limit)
+		if err != nil {
+			return nil, l.dbError(err)
+		}
+		return transactions, nil
+	})
+	l.personTxs[key] = loader
+	return loader
+}
+
+// fetchEvents 事件不入库，并发读取一批交易的回执并按 ABI 解码，节点上不存在的回执返回空列表
+func (l *graphqlLoaders) fetchEvents(hashes []string) (map[string][]DecodedLog, error) {
+	result := make(map[string][]DecodedLog, len(hashes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	sem := make(chan struct{}, graphqlNodeConcurrency)
+	for _, hash := range hashes {
+		wg.Add(1)
+		go func(hash string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			receipt, err := getTransactionReceipt(hash)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if receipt != nil {
+				result[hash] = decodeLogs(l.contractAbi, receipt.Logs)
+			}
+		}(hash)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		l.logger.Error("GraphQL 查询交易回执失败", "count", len(hashes), "error", firstErr)
+		return nil, errors.New("Error querying node")
+	}
+	return result, nil
+}
+
+// graphqlRequest GraphQL 请求，POST 时为 JSON 请求体，GET 时为同名 query 参数（variables 为 JSON 字符串）
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlLimits 查询的最大深度和复杂度
+type graphqlLimits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// graphqlCost 按 schema 计算查询的深度和复杂度：每个字段计 1，对象列表字段的子字段乘以 first 参数
+// （没有 first 参数时按 graphqlListEstimate），内省字段（__ 开头）不计入
+type graphqlCost struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func newGraphQLCost(schema *graphql.Schema, doc *ast.Document, variables map[string]interface{}) *graphqlCost {
+	c := &graphqlCost{schema: schema, fragments: make(map[string]*ast.FragmentDefinition), variables: make(map[string]interface{}, len(variables))}
+	for name, v := range variables {
+		c.variables[name] = v
+	}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[f.Name.Value] = f
+		}
+	}
+	return c
+}
+
+// operation 返回要执行的操作，文档已通过校验，多个操作时 operationName 必须匹配其中之一。
+// 请求中没有传入的变量按操作声明的默认值计算，与执行时一致
+func (c *graphqlCost) operation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+				c.variableDefaults(op)
+				return op
+			}
+		}
+	}
+	return nil
+}
+
+func (c *graphqlCost) variableDefaults(op *ast.OperationDefinition) {
+	for _, def := range op.VariableDefinitions {
+		name := def.Variable.Name.Value
+		if _, ok := c.variables[name]; ok {
+			continue
+		}
+		if v, ok := def.DefaultValue.(*ast.IntValue); ok {
+			if n, err := strconv.ParseFloat(v.Value, 64); err == nil {
+				c.variables[name] = n
+			}
+		}
+	}
+}
+
+// selection 返回选择集的最大深度和复杂度，depth 为选择集中字段所在的层数
+func (c *graphqlCost) selection(set *ast.SelectionSet, parent *graphql.Object, depth int) (int, int) {
+	maxDepth, cost := 0, 0
+	if set == nil || parent == nil {
+		return maxDepth, cost
+	}
+	for _, sel := range set.Selections {
+		d, n := 0, 0
+		switch s := sel.(type) {
+		case *ast.Field:
+			d, n = c.field(s, parent, depth)
+		case *ast.InlineFragment:
+			d, n = c.selection(s.SelectionSet, c.typeCondition(s.TypeCondition, parent), depth)
+		case *ast.FragmentSpread:
+			if f, ok := c.fragments[s.Name.Value]; ok {
+				d, n = c.selection(f.SelectionSet, c.typeCondition(f.TypeCondition, parent), depth)
+			}
+		}
+		if d > maxDepth {
+			maxDepth = d
+		}
+		cost += n
+	}
+	return maxDepth, cost
+}
+
+func (c *graphqlCost) field(f *ast.Field, parent *graphql.Object, depth int) (int, int) {
+	if strings.HasPrefix(f.Name.Value, "__") {
+		return 0, 0
+	}
+	def, ok := parent.Fields()[f.Name.Value]
+	if !ok {
+		return depth, 1
+	}
+	t, isList := def.Type, false
+	for {
+		if nn, ok := t.(*graphql.NonNull); ok {
+			t = nn.OfType
+		} else if list, ok := t.(*graphql.List); ok {
+			t, isList = list.OfType, true
+		} else {
+			break
+		}
+	}
+	obj, ok := t.(*graphql.Object)
+	if !ok {
+		return depth, 1
+	}
+	childDepth, childCost := c.selection(f.SelectionSet, obj, depth+1)
+	if isList {
+		childCost *= c.listSize(f, def)
+	}
+	if childDepth < depth {
+		childDepth = depth
+	}
+	return childDepth, 1 + childCost
+}
+
+// listSize 列表字段的 first 参数，可以是字面量或变量，未传入时取参数默认值。
+// 超出范围的 first 会在执行时报错，这里限制在 [1, graphqlMaxFirst]，避免负数或 0 抵消其他字段的复杂度
+func (c *graphqlCost) listSize(f *ast.Field, def *graphql.FieldDefinition) int {
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				return clampFirst(n)
+			}
+		case *ast.Variable:
+			value, ok := c.variables[v.Name.Value]
+			if !ok {
+				// 没有传入也没有默认值时按未传入 first 处理
+				break
+			}
+			if n, ok := value.(float64); ok {
+				return clampFirst(int(n))
+			}
+			// 无法判断执行时的取值，按最大值计算
+			return graphqlMaxFirst
+		}
+	}
+	for _, arg := range def.Args {
+		if arg.Name() == "first" {
+			if n, ok := arg.DefaultValue.(int); ok {
+				return clampFirst(n)
+			}
+		}
+	}
+	return graphqlListEstimate
+}
+
+func clampFirst(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > graphqlMaxFirst {
+		return graphqlMaxFirst
+	}
+	return n
+}
+
+func (c *graphqlCost) typeCondition(cond *ast.Named, parent *graphql.Object) *graphql.Object {
+	if cond == nil {
+		return parent
+	}
+	if obj, ok := c.schema.Type(cond.Name.Value).(*graphql.Object); ok {
+		return obj
+	}
+	return parent
+}
+
+// checkGraphQLLimits 超出深度或复杂度限制时返回错误，extensions.code 为 query_too_deep 或 query_too_complex
+func checkGraphQLLimits(schema *graphql.Schema, doc *ast.Document, req graphqlRequest, limits graphqlLimits) []gqlerrors.FormattedError {
+	c := newGraphQLCost(schema, doc, req.Variables)
+	op := c.operation(doc, req.OperationName)
+	if op == nil {
+		return nil
+	}
+	depth, complexity := c.selection(op.SelectionSet, schema.QueryType(), 1)
+	errs := make([]gqlerrors.FormattedError, 0)
+	if depth > limits.MaxDepth {
+		errs = append(errs, gqlerrors.FormattedError{
+			Message:    fmt.Sprintf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth),
+			Extensions: map[string]interface{}{"code": "query_too_deep", "depth": depth, "limit": limits.MaxDepth},
+		})
+	}
+	if complexity > limits.MaxComplexity {
+		errs = append(errs, gqlerrors.FormattedError{
+			Message:    fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity),
+			Extensions: map[string]interface{}{"code": "query_too_complex", "complexity": complexity, "limit": limits.MaxComplexity},
+		})
+	}
+	return errs
+}
+
+// handleGraphQL 处理 /graphql。查询无法解析、未通过校验或超出深度/复杂度限制时返回 400，
+// 执行中的字段错误按 GraphQL 规范与 data 一起返回 200
+func (srv *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	var req graphqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		queryValues := r.URL.Query()
+		req.Query = queryValues.Get("query")
+		req.OperationName = queryValues.Get("operationName")
+		if v := queryValues.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Invalid variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, graphqlMaxRequestBytes)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "Request body too large")
+				return
+			}
+			writeError(w, http.StatusBadRequest, errCodeBadRequest, "Bad Request")
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Missing query")
+		return
+	}
+
+	result, status := srv.executeGraphQL(r.Context(), l, req, graphqlLimits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
+	if len(result.Errors) > 0 {
+		l.Debug("GraphQL 查询返回错误", "status", status, "errors", len(result.Errors), "first_error", result.Errors[0].Message)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// executeGraphQL 解析、校验、检查限制后执行查询，返回结果和 HTTP 状态码
+func (srv *Server) executeGraphQL(ctx context.Context, l *slog.Logger, req graphqlRequest, limits graphqlLimits) (*graphql.Result, int) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, http.StatusBadRequest
+	}
+	validation := graphql.ValidateDocument(&graphqlSchema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, http.StatusBadRequest
+	}
+	if errs := checkGraphQLLimits(&graphqlSchema, doc, req, limits); len(errs) > 0 {
+		return &graphql.Result{Errors: errs}, http.StatusBadRequest
+	}
+
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		l.Error("加载合约失败", "error", err)
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: "Internal Server Error"}}}, http.StatusInternalServerError
+	}
+	loaders := newGraphQLLoaders(srv, l, contractAbi)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphqlSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, graphqlCtxKey{}, loaders),
+	})
+	return result, http.StatusOK
+}
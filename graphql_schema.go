@@ -0,0 +1,661 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQL 列表字段 first 参数的默认值和上限，与 REST 接口的 pagesize 一致
+const (
+	graphqlDefaultFirst = 10
+	graphqlMaxFirst     = 100
+)
+
+// IndexedTransaction bc_block_transactions 的一行，GraphQL 的 Transaction 和 VitalsSample 都由它解析
+type IndexedTransaction struct {
+	Id              int64
+	BlockNumber     int
+	Hash            string
+	From            string
+	To              string
+	Input           string
+	DecodeInput     json.RawMessage
+	MethodId        string
+	Output          string
+	DecodeOutput    json.RawMessage
+	Status          int
+	GasUsed         string
+	ImportTime      int64
+	HeartRate       string
+	BreathRate      string
+	SleepState      int
+	HeartChange     string
+	SleepBreathing  string
+	PersonId        int
+	ContactName     string
+	ContactIdentity string
+}
+
+// isVitals 成功的 shareData 交易才是一次体征上报
+func (tx IndexedTransaction) isVitals() bool {
+	return tx.MethodId == contractMethodId && tx.Status == 0
+}
+
+// detail 补全方法名，入库时未解码的 input/output 按 ABI 即时解码
+func (tx IndexedTransaction) detail(l *graphqlLoaders) *TransactionDetail {
+	d := &TransactionDetail{To: tx.To, Input: tx.Input, Output: tx.Output, DecodeInput: tx.DecodeInput, DecodeOutput: tx.DecodeOutput}
+	decodeTransactionDetail(l.contractAbi, d)
+	return d
+}
+
+// longScalar 64 位整数，用于毫秒时间戳（GraphQL 的 Int 只有 32 位）
+var longScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Long",
+	Description: "64 位整数",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case int64:
+			return v
+		case int:
+			return int64(v)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case float64:
+			return int64(v)
+		case int:
+			return int64(v)
+		case int64:
+			return v
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if v, ok := valueAST.(*ast.IntValue); ok {
+			if n, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				return n
+			}
+		}
+		return nil
+	},
+})
+
+// jsonScalar 原样输出的 JSON 值，用于 ABI 解码结果和事件参数
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "任意 JSON 值",
+	Serialize: func(value interface{}) interface{} {
+		if v, ok := value.(json.RawMessage); ok && len(v) == 0 {
+			return nil
+		}
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+var firstArg = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphqlDefaultFirst, Description: "返回条数，最大 100"}
+
+// firstOf 读取 first 参数，超出范围时返回错误
+func firstOf(p graphql.ResolveParams) (int, error) {
+	first, _ := p.Args["first"].(int)
+	if first <= 0 || first > graphqlMaxFirst {
+		return 0, fmt.Errorf("first must be between 1 and %d", graphqlMaxFirst)
+	}
+	return first, nil
+}
+
+func addressArg(p graphql.ResolveParams, name string) (string, error) {
+	address, _ := p.Args[name].(string)
+	if address == "" {
+		return "", nil
+	}
+	if !isValidAddress(address) {
+		return "", fmt.Errorf("invalid address %q", address)
+	}
+	return strings.ToLower(address), nil
+}
+
+var (
+	blockType       *graphql.Object
+	transactionType *graphql.Object
+	accountType     *graphql.Object
+	personType      *graphql.Object
+	vitalsType      *graphql.Object
+	eventType       *graphql.Object
+)
+
+// defineGraphQLTypes 定义对象类型，类型之间互相引用，字段用 FieldsThunk 延迟到构建 schema 时解析
+func defineGraphQLTypes() {
+	blockType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Block",
+		Description: "已同步到本地索引的区块",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"number": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"hash":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"transactionCount": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						load := loadersFrom(p).blockTransactions.load(p.Source.(BlockDetail).Number)
+						return func() (interface{}, error) {
+							txs, _, err := load()
+							return len(txs), err
+						}, nil
+					},
+				},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+					Description: "区块内的交易，按入库顺序",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return listThunk(loadersFrom(p).blockTransactions, p.Source.(BlockDetail).Number), nil
+					},
+				},
+			}
+		}),
+	})
+
+	transactionType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Transaction",
+		Description: "已同步到本地索引的交易",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"hash":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"blockNumber": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"block": &graphql.Field{
+					Type: blockType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return valueThunk(loadersFrom(p).blocks, p.Source.(IndexedTransaction).BlockNumber), nil
+					},
+				},
+				"from": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"to":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"sender": &graphql.Field{
+					Type:        accountType,
+					Description: "发送方帐户，不在帐户表中时为 null",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return valueThunk(loadersFrom(p).accounts, p.Source.(IndexedTransaction).From), nil
+					},
+				},
+				"recipient": &graphql.Field{
+					Type:        accountType,
+					Description: "接收方帐户，不在帐户表中时为 null",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return valueThunk(loadersFrom(p).accounts, p.Source.(IndexedTransaction).To), nil
+					},
+				},
+				"input":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"methodId": &graphql.Field{Type: graphql.String},
+				"method": &graphql.Field{
+					Type:        graphql.String,
+					Description: "合约方法名，不是合约调用时为 null",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						tx := p.Source.(IndexedTransaction)
+						if method := contractMethod(loadersFrom(p).contractAbi, tx.To, tx.Input); method != nil {
+							return method.RawName, nil
+						}
+						return nil, nil
+					},
+				},
+				"decodedInput": &graphql.Field{
+					Type:        jsonScalar,
+					Description: "按合约 ABI 解码的调用参数",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(IndexedTransaction).detail(loadersFrom(p)).DecodeInput, nil
+					},
+				},
+				"output": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"decodedOutput": &graphql.Field{
+					Type:        jsonScalar,
+					Description: "按合约 ABI 解码的返回值",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(IndexedTransaction).detail(loadersFrom(p)).DecodeOutput, nil
+					},
+				},
+				"status":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "回执状态，0 为成功，-1 为回执尚未同步"},
+				"gasUsed":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"importTime": &graphql.Field{Type: graphql.NewNonNull(longScalar), Description: "毫秒时间戳"},
+				"cursor": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "作为 transactions 的 after 参数获取下一页",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						tx := p.Source.(IndexedTransaction)
+						return encodeTransactionCursor(TransactionCursor{BlockNum: tx.BlockNumber, Id: tx.Id}), nil
+					},
+				},
+				"vitals": &graphql.Field{
+					Type:        vitalsType,
+					Description: "成功的 shareData 交易上报的体征数据，其他交易为 null",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if tx := p.Source.(IndexedTransaction); tx.isVitals() {
+							return tx, nil
+						}
+						return nil, nil
+					},
+				},
+				"person": &graphql.Field{
+					Type:    personType,
+					Resolve: resolveTransactionPerson,
+				},
+				"events": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(eventType))),
+					Description: "回执中的事件，事件不入库，从节点读取",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return listThunk(loadersFrom(p).events, p.Source.(IndexedTransaction).Hash), nil
+					},
+				},
+			}
+		}),
+	})
+
+	accountType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Account",
+		Description: "帐户表中的帐户",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"balance": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"cred":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"shareNum": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(AccountResponse).ShareNnum, nil
+					},
+				},
+				"rank": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "排行榜名次，只在 ranking 中返回",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if a := p.Source.(AccountResponse); a.Rank > 0 {
+							return a.Rank, nil
+						}
+						return nil, nil
+					},
+				},
+				"score": &graphql.Field{
+					Type:        graphql.Int,
+					Description: "排行榜得分，只在 ranking 中返回",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if a := p.Source.(AccountResponse); a.Rank > 0 {
+							return a.Score, nil
+						}
+						return nil, nil
+					},
+				},
+				"person": &graphql.Field{
+					Type:        personType,
+					Description: "最近一次关联的人员",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return valueThunk(loadersFrom(p).accountPersons, p.Source.(AccountResponse).Address), nil
+					},
+				},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+					Description: "作为发送方的最新交易",
+					Args:        graphql.FieldConfigArgument{"first": firstArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						first, err := firstOf(p)
+						if err != nil {
+							return nil, err
+						}
+						return listThunk(loadersFrom(p).senderTransactions(false, first), p.Source.(AccountResponse).Address), nil
+					},
+				},
+				"vitals": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(vitalsType))),
+					Description: "最新的体征上报",
+					Args:        graphql.FieldConfigArgument{"first": firstArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						first, err := firstOf(p)
+						if err != nil {
+							return nil, err
+						}
+						return listThunk(loadersFrom(p).senderTransactions(true, first), p.Source.(AccountResponse).Address), nil
+					},
+				},
+			}
+		}),
+	})
+
+	personType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Person",
+		Description: "shareData 上报中关联的人员，联系人信息取最近一次上报",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(LinkedPerson).PersonId, nil
+					},
+				},
+				"contactName":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"contactIdentity": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"accounts": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(accountType))),
+					Description: "为该人员上报过数据的帐户",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return listThunk(loadersFrom(p).personAccounts, p.Source.(LinkedPerson).PersonId), nil
+					},
+				},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+					Description: "关联该人员的最新交易",
+					Args:        graphql.FieldConfigArgument{"first": firstArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						first, err := firstOf(p)
+						if err != nil {
+							return nil, err
+						}
+						return listThunk(loadersFrom(p).personTransactions(false, first), p.Source.(LinkedPerson).PersonId), nil
+					},
+				},
+				"vitals": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(vitalsType))),
+					Description: "最新的体征上报",
+					Args:        graphql.FieldConfigArgument{"first": firstArg},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						first, err := firstOf(p)
+						if err != nil {
+							return nil, err
+						}
+						return listThunk(loadersFrom(p).personTransactions(true, first), p.Source.(LinkedPerson).PersonId), nil
+					},
+				},
+			}
+		}),
+	})
+
+	vitalsType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "VitalsSample",
+		Description: "一次 shareData 上报的体征数据",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"transactionHash": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(IndexedTransaction).Hash, nil
+					},
+				},
+				"blockNumber":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"importTime":     &graphql.Field{Type: graphql.NewNonNull(longScalar), Description: "毫秒时间戳"},
+				"heartRate":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"breathRate":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"sleepState":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"heartChange":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"sleepBreathing": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"person":         &graphql.Field{Type: personType, Resolve: resolveTransactionPerson},
+				"transaction": &graphql.Field{
+					Type: graphql.NewNonNull(transactionType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source, nil
+					},
+				},
+				"account": &graphql.Field{
+					Type:        accountType,
+					Description: "上报的帐户，不在帐户表中时为 null",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return valueThunk(loadersFrom(p).accounts, p.Source.(IndexedTransaction).From), nil
+					},
+				},
+			}
+		}),
+	})
+
+	eventType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Event",
+		Description: "交易回执中的事件，本合约的事件按 ABI 解码出事件名和参数",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"topics":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"data":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if e := p.Source.(DecodedLog); e.Event != "" {
+						return e.Event, nil
+					}
+					return nil, nil
+				},
+			},
+			"args": &graphql.Field{
+				Type: jsonScalar,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if e := p.Source.(DecodedLog); e.Args != nil {
+						return e.Args, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+}
+
+// resolveTransactionPerson 交易本身带有人员信息，不需要额外查询
+func resolveTransactionPerson(p graphql.ResolveParams) (interface{}, error) {
+	tx := p.Source.(IndexedTransaction)
+	if tx.PersonId <= 0 {
+		return nil, nil
+	}
+	return LinkedPerson{PersonId: tx.PersonId, ContactName: tx.ContactName, ContactIdentity: tx.ContactIdentity}, nil
+}
+
+// transactionFilterArgs Query.transactions 的过滤参数，与 getTransByAddress 相同
+var transactionFilterArgs = graphql.FieldConfigArgument{
+	"from":       &graphql.ArgumentConfig{Type: graphql.String, Description: "发送方"},
+	"to":         &graphql.ArgumentConfig{Type: graphql.String, Description: "接收方"},
+	"party":      &graphql.ArgumentConfig{Type: graphql.String, Description: "发送方或接收方"},
+	"method":     &graphql.ArgumentConfig{Type: graphql.String, Description: "合约方法名或 method_id"},
+	"person":     &graphql.ArgumentConfig{Type: graphql.Int, Description: "person_id"},
+	"status":     &graphql.ArgumentConfig{Type: graphql.Int},
+	"blockStart": &graphql.ArgumentConfig{Type: graphql.Int, Description: "起始区块（含）"},
+	"blockEnd":   &graphql.ArgumentConfig{Type: graphql.Int, Description: "结束区块（含）"},
+	"start":      &graphql.ArgumentConfig{Type: longScalar, Description: "import_time 起始毫秒时间戳（含）"},
+	"end":        &graphql.ArgumentConfig{Type: longScalar, Description: "import_time 结束毫秒时间戳（不含）"},
+	"ascending":  &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+	"first":      firstArg,
+	"after":      &graphql.ArgumentConfig{Type: graphql.String, Description: "上一页最后一条交易的 cursor"},
+}
+
+// transactionFilterOf 将 Query.transactions 的参数转换为 TransactionFilter，from、to、party、person 至少指定一个
+func transactionFilterOf(p graphql.ResolveParams) (TransactionFilter, error) {
+	f := TransactionFilter{}
+	var err error
+	addressArgs := []struct {
+		name string
+		dest *string
+	}{
+		{"from", &f.From},
+		{"to", &f.To},
+		{"party", &f.Party},
+	}
+	for _, a := range addressArgs {
+		if *a.dest, err = addressArg(p, a.name); err != nil {
+			return f, err
+		}
+	}
+	if method, _ := p.Args["method"].(string); method != "" {
+		if f.MethodId, err = methodIdByName(method); err != nil {
+			return f, err
+		}
+	}
+	if status, ok := p.Args["status"].(int); ok {
+		f.Status = &status
+	}
+	f.PersonId, _ = p.Args["person"].(int)
+	f.BlockStart, _ = p.Args["blockStart"].(int)
+	f.BlockEnd, _ = p.Args["blockEnd"].(int)
+	f.Start, _ = p.Args["start"].(int64)
+	f.End, _ = p.Args["end"].(int64)
+	f.Ascending, _ = p.Args["ascending"].(bool)
+	if !f.hasSubject() {
+		return f, errMissingTransactionSubject
+	}
+	if f.Limit, err = firstOf(p); err != nil {
+		return f, err
+	}
+	if after, _ := p.Args["after"].(string); after != "" {
+		if f.Cursor, err = decodeTransactionCursor(after); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// newGraphQLSchema 根查询。单个对象不存在时返回 null，列表不存在时返回空列表
+func newGraphQLSchema() (graphql.Schema, error) {
+	defineGraphQLTypes()
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": &graphql.Field{
+				Type:        blockType,
+				Description: "按高度或哈希查询区块",
+				Args: graphql.FieldConfigArgument{
+					"number": &graphql.ArgumentConfig{Type: graphql.Int},
+					"hash":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					l := loadersFrom(p)
+					if hash, _ := p.Args["hash"].(string); hash != "" {
+						if !isValidHash(hash) {
+							return nil, fmt.Errorf("invalid block hash %q", hash)
+						}
+						b, err := l.srv.blocks.GetByHash(strings.ToLower(hash))
+						if err == sql.ErrNoRows {
+							return nil, nil
+						}
+						if err != nil {
+							return nil, l.dbError(err)
+						}
+						return *b, nil
+					}
+					number, ok := p.Args["number"].(int)
+					if !ok {
+						return nil, errors.New("number or hash is required")
+					}
+					return valueThunk(l.blocks, number), nil
+				},
+			},
+			"blocks": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(blockType))),
+				Description: "按高度降序的区块列表",
+				Args: graphql.FieldConfigArgument{
+					"first":  firstArg,
+					"before": &graphql.ArgumentConfig{Type: graphql.Int, Description: "只返回低于该高度的区块"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, err := firstOf(p)
+					if err != nil {
+						return nil, err
+					}
+					before, _ := p.Args["before"].(int)
+					l := loadersFrom(p)
+					blocks, err := l.srv.blocks.List(before, first)
+					if err != nil {
+						return nil, l.dbError(err)
+					}
+					return blocks, nil
+				},
+			},
+			"transaction": &graphql.Field{
+				Type: transactionType,
+				Args: graphql.FieldConfigArgument{
+					"hash": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					hash, _ := p.Args["hash"].(string)
+					if !isValidHash(hash) {
+						return nil, fmt.Errorf("invalid transaction hash %q", hash)
+					}
+					return valueThunk(loadersFrom(p).transactions, strings.ToLower(hash)), nil
+				},
+			},
+			"transactions": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+				Description: "按区块降序（ascending 为 true 时升序）的交易列表，from、to、party、person 至少指定一个，用最后一条的 cursor 作为 after 获取下一页",
+				Args:        transactionFilterArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					f, err := transactionFilterOf(p)
+					if err != nil {
+						return nil, err
+					}
+					l := loadersFrom(p)
+					transactions, err := l.srv.txs.Query(f)
+					if err != nil {
+						return nil, l.dbError(err)
+					}
+					return transactions, nil
+				},
+			},
+			"account": &graphql.Field{
+				Type: accountType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address, err := addressArg(p, "address")
+					if err != nil {
+						return nil, err
+					}
+					return valueThunk(loadersFrom(p).accounts, address), nil
+				},
+			},
+			"ranking": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(accountType))),
+				Description: "排行榜，参数与 /accountRanking 相同",
+				Args: graphql.FieldConfigArgument{
+					"sort":   &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "balance", Description: "balance、cred 或 share_num"},
+					"window": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "all", Description: "all、7d 或 30d"},
+					"first":  firstArg,
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					sort, _ := p.Args["sort"].(string)
+					window, _ := p.Args["window"].(string)
+					opts, err := newRankingOptions(sort, window)
+					if err != nil {
+						return nil, err
+					}
+					first, err := firstOf(p)
+					if err != nil {
+						return nil, err
+					}
+					offset, _ := p.Args["offset"].(int)
+					if offset < 0 {
+						return nil, fmt.Errorf("invalid offset %d", offset)
+					}
+					l := loadersFrom(p)
+					accounts, err := l.srv.accounts.Ranking(opts, offset, first)
+					if err != nil {
+						return nil, l.dbError(err)
+					}
+					return accounts, nil
+				},
+			},
+			"person": &graphql.Field{
+				Type: personType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(int)
+					return valueThunk(loadersFrom(p).persons, id), nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+func TestGraphQLCostClampsFirst(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		cost      int
+	}{
+		{"literal", `{ blocks(first: 5) { number hash } }`, nil, 1 + 2*5},
+		{"default", `{ blocks { number } }`, nil, 1 + graphqlDefaultFirst},
+		{"zero", `{ blocks(first: 0) { number } }`, nil, 1 + 1},
+		{"too large", `{ blocks(first: 100000) { number } }`, nil, 1 + graphqlMaxFirst},
+		{"variable", `query($n: Int) { blocks(first: $n) { number } }`, map[string]interface{}{"n": float64(1000)}, 1 + graphqlMaxFirst},
+		{"variable default", `query($n: Int = 100) { ranking(first: $n) { transactions(first: $n) { hash } } }`, nil, 1 + 100*(1+100*1)},
+		{"variable overrides default", `query($n: Int = 100) { blocks(first: $n) { number } }`, map[string]interface{}{"n": float64(5)}, 1 + 5},
+		{"variable without default", `query($n: Int) { blocks(first: $n) { number } }`, nil, 1 + graphqlDefaultFirst},
+		{"variable not a number", `query($n: Int) { blocks(first: $n) { number } }`, map[string]interface{}{"n": "5"}, 1 + graphqlMaxFirst},
+		// 负数的 first 不能抵消其他别名字段的复杂度
+		{"negative alias", `{ a: blocks(first: 100) { number hash } b: blocks(first: -100000) { number hash } }`, nil, (1 + 2*100) + (1 + 2*1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(tt.query)})})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := newGraphQLCost(&graphqlSchema, doc, tt.variables)
+			_, cost := c.selection(c.operation(doc, "").SelectionSet, graphqlSchema.QueryType(), 1)
+			if cost != tt.cost {
+				t.Errorf("cost = %d, want %d", cost, tt.cost)
+			}
+		})
+	}
+}
+
+func TestGraphQLTransactionsRequiresSubject(t *testing.T) {
+	saved := abiStr
+	abiStr = testContractAbi
+	t.Cleanup(func() { abiStr = saved })
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	srv := NewServer(s)
+
+	tests := []struct {
+		query  string
+		hashes int
+	}{
+		{`{ transactions(first: 10) { hash } }`, -1},
+		{`{ transactions(first: 10, start: 1) { hash } }`, -1},
+		{`{ transactions(first: 10, party: "` + testAddressA + `") { hash } }`, 4},
+		{`{ transactions(first: 10, person: 7) { hash } }`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			body, _ := json.Marshal(graphqlRequest{Query: tt.query})
+			r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			srv.routes().ServeHTTP(w, r)
+			var resp struct {
+				Data struct {
+					Transactions []struct{ Hash string }
+				}
+				Errors []struct{ Message string }
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%v: %s", err, w.Body.String())
+			}
+			if tt.hashes < 0 {
+				if len(resp.Errors) != 1 || resp.Errors[0].Message != errMissingTransactionSubject.Msg {
+					t.Errorf("errors = %+v", resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) != 0 || len(resp.Data.Transactions) != tt.hashes {
+				t.Errorf("response = %s", w.Body.String())
+			}
+		})
+	}
+}
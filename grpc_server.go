@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"bc/bcpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// errCodeGRPCStatus 服务层错误码对应的 gRPC 状态码，未列出的按 Internal 处理
+var errCodeGRPCStatus = map[string]codes.Code{
+	errCodeBadRequest:          codes.InvalidArgument,
+	errCodeInvalidParameter:    codes.InvalidArgument,
+	errCodeInvalidAddress:      codes.InvalidArgument,
+	errCodeInvalidHash:         codes.InvalidArgument,
+	errCodeUnauthorized:        codes.Unauthenticated,
+	errCodeNotFound:            codes.NotFound,
+	errCodeMethodNotAllowed:    codes.Unimplemented,
+	errCodePayloadTooLarge:     codes.ResourceExhausted,
+	errCodeTransactionReverted: codes.FailedPrecondition,
+	errCodeNode:                codes.Unavailable,
+	errCodeNotReady:            codes.Unavailable,
+}
+
+// grpcService 实现 bcpb.BcServiceServer，与 HTTP 处理函数调用同一套服务层方法
+type grpcService struct {
+	bcpb.UnimplementedBcServiceServer
+	srv *Server
+}
+
+// initGRPC 配置了 GRPC_PORT 时在后台启动 gRPC 服务
+func initGRPC(srv *Server) {
+	grpcPort := cfg.GRPCPort
+	if grpcPort == "" {
+		return
+	}
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		logFatal(logger, "Failed to start gRPC server", "error", err)
+	}
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcUnaryLog),
+		grpc.ChainStreamInterceptor(grpcStreamLog),
+	)
+	bcpb.RegisterBcServiceServer(gs, &grpcService{srv: srv})
+	logger.Info("gRPC 服务端口", "port", grpcPort)
+	go func() {
+		if err := gs.Serve(lis); err != nil {
+			logFatal(logger, "gRPC server stopped", "error", err)
+		}
+	}()
+}
+
+// grpcRequestIdKey context 中 request id 的键，注册任务需要记录 request id
+type grpcRequestIdKey struct{}
+
+// grpcContext 与 withRequestLog 相同：从 x-request-id 元数据读取或生成 request id，
+// 回写到响应头，并将带有该字段的 logger 放入 context
+func grpcContext(ctx context.Context) context.Context {
+	requestId := grpcMetadata(ctx, "x-request-id")
+	if requestId == "" {
+		requestId = newRequestId()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestId))
+	l := logger.With(logKeyRequestId, requestId)
+	ctx = context.WithValue(ctx, grpcRequestIdKey{}, requestId)
+	return context.WithValue(ctx, logCtxKey{}, l)
+}
+
+func grpcMetadata(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func grpcUnaryLog(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = grpcContext(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	loggerFromContext(ctx).Debug("request handled", "method", info.FullMethod, "code", status.Code(err).String(), "duration_ms", time.Since(start).Milliseconds())
+	return resp, err
+}
+
+// grpcServerStream 替换 stream 的 context，使处理函数能取到带 request id 的 logger
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func grpcStreamLog(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := grpcContext(ss.Context())
+	start := time.Now()
+	err := handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+	loggerFromContext(ctx).Debug("stream closed", "method", info.FullMethod, "code", status.Code(err).String(), "duration_ms", time.Since(start).Milliseconds())
+	return err
+}
+
+// grpcError 将服务层错误转换为 gRPC 状态，带底层错误时记录日志
+func grpcError(ctx context.Context, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	se := asServiceError(err)
+	if se.Err != nil {
+		loggerFromContext(ctx).Error(se.Msg, "error", se.Err)
+	}
+	code, ok := errCodeGRPCStatus[se.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, se.Msg)
+}
+
+func (g *grpcService) Register(ctx context.Context, req *bcpb.RegisterRequest) (*bcpb.RegisterJob, error) {
+	caller := RegisterCaller{Caller: grpcMetadata(ctx, "x-caller-id")}
+	caller.RequestId, _ = ctx.Value(grpcRequestIdKey{}).(string)
+	if p, ok := peer.FromContext(ctx); ok {
+		caller.RemoteIP = p.Addr.String()
+	}
+	job, err := g.srv.submitRegister(loggerFromContext(ctx), Input{Address: req.Address, CallbackUrl: req.CallbackUrl}, caller)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &bcpb.RegisterJob{
+		Id:             job.Id,
+		Address:        job.Address,
+		Status:         job.Status,
+		Attempts:       int32(job.Attempts),
+		MaxAttempts:    int32(job.MaxAttempts),
+		NextRunAt:      job.NextRunAt,
+		TxHash:         job.TxHash,
+		ReceiptStatus:  int32(job.ReceiptStatus),
+		Result:         job.Result,
+		Error:          job.Error,
+		CallbackStatus: int32(job.CallbackStatus),
+		CreatedAt:      job.CreatedAt,
+		UpdatedAt:      job.UpdatedAt,
+		FinishedAt:     job.FinishedAt,
+	}, nil
+}
+
+func (g *grpcService) GetContractAddress(ctx context.Context, req *bcpb.GetContractAddressRequest) (*bcpb.GetContractAddressResponse, error) {
+	return &bcpb.GetContractAddressResponse{Address: contractAddress}, nil
+}
+
+// ListTransactions 请求字段转换为 HTTP 查询参数后按同一规则校验
+func (g *grpcService) ListTransactions(ctx context.Context, req *bcpb.ListTransactionsRequest) (*bcpb.ListTransactionsResponse, error) {
+	values := url.Values{}
+	set := func(key string, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	setInt := func(key string, value int64) {
+		if value != 0 {
+			values.Set(key, strconv.FormatInt(value, 10))
+		}
+	}
+	set("address", req.Address)
+	set("from", req.From)
+	set("to", req.To)
+	set("party", req.Party)
+	set("method", req.Method)
+	set("order", req.Order)
+	setInt("person", int64(req.Person))
+	setInt("block_start", int64(req.BlockStart))
+	setInt("block_end", int64(req.BlockEnd))
+	setInt("start", req.Start)
+	setInt("end", req.End)
+	setInt("page", int64(req.Page))
+	setInt("pagesize", int64(req.PageSize))
+	if req.Status != nil {
+		values.Set("status", strconv.Itoa(int(*req.Status)))
+	}
+	if req.Cursor != nil {
+		values.Set("cursor", *req.Cursor)
+	}
+
+	f, cursorMode, err := transactionFilterFromValues(values)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	result, err := g.srv.indexedTransactionPage(f, cursorMode, req.WithTotal)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	transactions, _ := result.List.([]IndexedTransaction)
+	resp := &bcpb.ListTransactionsResponse{
+		Transactions: make([]*bcpb.Transaction, 0, len(transactions)),
+		Page:         int32(result.Page),
+		PageSize:     int32(result.PageSize),
+		NextCursor:   result.NextCursor,
+	}
+	if result.Total != nil {
+		total := int32(*result.Total)
+		resp.Total = &total
+	}
+	for _, tx := range transactions {
+		resp.Transactions = append(resp.Transactions, &bcpb.Transaction{
+			BlockNum:        int32(tx.BlockNumber),
+			TransHash:       tx.Hash,
+			From:            tx.From,
+			To:              tx.To,
+			Status:          int32(tx.Status),
+			Input:           tx.Input,
+			Output:          tx.Output,
+			MethodId:        tx.MethodId,
+			GasUsed:         tx.GasUsed,
+			ImportTime:      tx.ImportTime,
+			HeartRate:       tx.HeartRate,
+			BreathRate:      tx.BreathRate,
+			SleepState:      int32(tx.SleepState),
+			HeartChange:     tx.HeartChange,
+			SleepBreathing:  tx.SleepBreathing,
+			PersonId:        int32(tx.PersonId),
+			ContactName:     tx.ContactName,
+			ContactIdentity: tx.ContactIdentity,
+		})
+	}
+	return resp, nil
+}
+
+func grpcAccount(a AccountResponse) *bcpb.Account {
+	return &bcpb.Account{
+		Address:  a.Address,
+		Balance:  int32(a.Balance),
+		Cred:     int32(a.Cred),
+		ShareNum: int32(a.ShareNnum),
+		Rank:     int32(a.Rank),
+		Score:    int32(a.Score),
+	}
+}
+
+func grpcAccounts(accounts []AccountResponse) []*bcpb.Account {
+	result := make([]*bcpb.Account, 0, len(accounts))
+	for _, a := range accounts {
+		result = append(result, grpcAccount(a))
+	}
+	return result
+}
+
+func (g *grpcService) GetRanking(ctx context.Context, req *bcpb.GetRankingRequest) (*bcpb.GetRankingResponse, error) {
+	opts, err := newRankingOptions(req.Sort, req.Window)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	result, _, _, err := g.srv.rankingPage(opts, int(req.Page), int(req.PageSize))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &bcpb.GetRankingResponse{
+		Accounts: grpcAccounts(result.List),
+		Page:     int32(result.Page),
+		PageSize: int32(result.PageSize),
+		Total:    int32(result.Total),
+		Sort:     result.Sort,
+		Window:   result.Window,
+	}, nil
+}
+
+func (g *grpcService) GetAccountRank(ctx context.Context, req *bcpb.GetAccountRankRequest) (*bcpb.GetAccountRankResponse, error) {
+	opts, err := newRankingOptions(req.Sort, req.Window)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	neighbours := -1
+	if req.Neighbours != nil {
+		neighbours = int(*req.Neighbours)
+	}
+	result, err := g.srv.accountRank(opts, req.Address, neighbours)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &bcpb.GetAccountRankResponse{
+		Account:    grpcAccount(result.Account),
+		Neighbours: grpcAccounts(result.Neighbours),
+		Sort:       result.Sort,
+		Window:     result.Window,
+		Total:      int32(result.Total),
+	}, nil
+}
+
+func (g *grpcService) WatchBlocks(req *bcpb.WatchBlocksRequest, stream bcpb.BcService_WatchBlocksServer) error {
+	ctx := stream.Context()
+	l := loggerFromContext(ctx)
+	l.Info("开始推送区块", "from_block", req.FromBlock)
+	err := g.srv.watchBlocks(ctx, int(req.FromBlock), func(b *BlockDetail) error {
+		block := &bcpb.Block{
+			Number:       int32(b.Number),
+			Hash:         b.Hash,
+			TxCount:      int32(b.TxCount),
+			Transactions: make([]*bcpb.BlockTransaction, 0, len(b.Transactions)),
+		}
+		for _, tx := range b.Transactions {
+			block.Transactions = append(block.Transactions, &bcpb.BlockTransaction{
+				TransHash:  tx.Hash,
+				From:       tx.From,
+				To:         tx.To,
+				MethodId:   tx.MethodId,
+				Method:     tx.Method,
+				Status:     int32(tx.Status),
+				GasUsed:    tx.GasUsed,
+				ImportTime: int64(tx.ImportTime),
+			})
+		}
+		return stream.Send(block)
+	})
+	if status.Code(err) != codes.Unknown {
+		// Send 返回的已经是 gRPC 状态
+		return err
+	}
+	return grpcError(ctx, err)
+}
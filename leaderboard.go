@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 窗口排行榜缓存时长
+const rankingWindowCacheTTL = 60 * time.Second
+
+// Leaderboard 内存中的全时段排行榜，每个排序字段维护一份按 score 降序、address 升序的有序列表。
+// synUpdateAccount 更新帐户时增量调整位置，另有定时全量重载兜底（例如同步任务在其它进程运行时）
+type Leaderboard struct {
+	mu       sync.RWMutex
+	loaded   bool
+	accounts map[string]*AccountResponse
+	sorted   map[string][]*AccountResponse
+	version  int64
+	modified time.Time
+}
+
+type rankingCacheEntry struct {
+	accounts []AccountResponse
+	total    int
+	modified time.Time
+	expires  time.Time
+}
+
+var (
+	leaderboard = &Leaderboard{}
+
+	rankingCacheMu sync.Mutex
+	rankingCache   = make(map[string]rankingCacheEntry)
+)
+
+func accountScore(a *AccountResponse, key string) int {
+	switch key {
+	case "cred":
+		return a.Cred
+	case "share_num":
+		return a.ShareNnum
+	default:
+		return a.Balance
+	}
+}
+
+func rankLess(a *AccountResponse, b *AccountResponse, key string) bool {
+	sa, sb := accountScore(a, key), accountScore(b, key)
+	if sa != sb {
+		return sa > sb
+	}
+	return a.Address < b.Address
+}
+
+func (lb *Leaderboard) search(key string, a *AccountResponse) int {
+	list := lb.sorted[key]
+	return sort.Search(len(list), func(i int) bool { return !rankLess(list[i], a, key) })
+}
+
+// initLeaderboard 加载排行榜并启动定时重载
+func initLeaderboard(accounts AccountRepo) {
+	if err := leaderboard.reload(accounts); err != nil {
+		logger.Error("加载排行榜失败", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Leaderboard.ReloadMinutes) * time.Minute)
+		for {
+			<-ticker.C // 等待计时器触发
+			if err := leaderboard.reload(accounts); err != nil {
+				logger.Error("加载排行榜失败", "error", err)
+			}
+		}
+	}()
+}
+
+// reload 从 bc_block_account 全量重建排行榜，数据没有变化时保留原版本号以免 ETag 失效
+func (lb *Leaderboard) reload(repo AccountRepo) error {
+	all, err := repo.All()
+	if err != nil {
+		return err
+	}
+	accounts := make(map[string]*AccountResponse, len(all))
+	for i := range all {
+		accounts[all[i].Address] = &all[i]
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if lb.loaded && sameAccounts(lb.accounts, accounts) {
+		return nil
+	}
+	lb.accounts = accounts
+	lb.sorted = make(map[string][]*AccountResponse)
+	for key := range rankingSortKeys {
+		list := make([]*AccountResponse, 0, len(accounts))
+		for _, a := range accounts {
+			list = append(list, a)
+		}
+		sort.Slice(list, func(i, j int) bool { return rankLess(list[i], list[j], key) })
+		lb.sorted[key] = list
+	}
+	lb.loaded = true
+	lb.version++
+	lb.modified = time.Now()
+	return nil
+}
+
+func sameAccounts(a map[string]*AccountResponse, b map[string]*AccountResponse) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for address, x := range a {
+		y, ok := b[address]
+		if !ok || x.Balance != y.Balance || x.Cred != y.Cred || x.ShareNnum != y.ShareNnum {
+			return false
+		}
+	}
+	return true
+}
+
+// update 增量更新一个帐户：从各有序列表中移除旧位置，更新后二分插入新位置
+func (lb *Leaderboard) update(address string, balance int, cred int, shareNum int) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if !lb.loaded {
+		return
+	}
+
+	a, ok := lb.accounts[address]
+	if ok {
+		if a.Balance == balance && a.Cred == cred && a.ShareNnum == shareNum {
+			return
+		}
+		for key, list := range lb.sorted {
+			i := lb.search(key, a)
+			if i < len(list) && list[i] == a {
+				lb.sorted[key] = append(list[:i], list[i+1:]...)
+			}
+		}
+	} else {
+		a = &AccountResponse{Address: address}
+		lb.accounts[address] = a
+	}
+	a.Balance, a.Cred, a.ShareNnum = balance, cred, shareNum
+	for key, list := range lb.sorted {
+		i := lb.search(key, a)
+		list = append(list, nil)
+		copy(list[i+1:], list[i:])
+		list[i] = a
+		lb.sorted[key] = list
+	}
+	lb.version++
+	lb.modified = time.Now()
+}
+
+// page 返回 [offset, offset+limit) 区间的帐户及总数
+func (lb *Leaderboard) page(key string, offset int, limit int) ([]AccountResponse, int) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	list := lb.sorted[key]
+	accounts := make([]AccountResponse, 0, limit)
+	for i := offset; i < offset+limit && i < len(list); i++ {
+		a := *list[i]
+		a.Rank = i + 1
+		a.Score = accountScore(&a, key)
+		accounts = append(accounts, a)
+	}
+	return accounts, len(list)
+}
+
+// rank 返回帐户名次，帐户不存在时返回 0
+func (lb *Leaderboard) rank(key string, address string) int {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	a, ok := lb.accounts[address]
+	if !ok {
+		// 链上地址为小写
+		a, ok = lb.accounts[strings.ToLower(address)]
+	}
+	if !ok {
+		return 0
+	}
+	return lb.search(key, a) + 1
+}
+
+func (lb *Leaderboard) state() (loaded bool, version int64, modified time.Time) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.loaded, lb.version, lb.modified
+}
+
+// cachedWindowRanking 窗口排行榜按查询条件缓存 rankingWindowCacheTTL
+func cachedWindowRanking(repo AccountRepo, opts RankingOptions, offset int, limit int) (rankingCacheEntry, error) {
+	key := fmt.Sprintf("%s|%s|%d|%d", opts.Sort, opts.Window, offset, limit)
+	now := time.Now()
+	rankingCacheMu.Lock()
+	entry, ok := rankingCache[key]
+	rankingCacheMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry, nil
+	}
+
+	accounts, err := repo.Ranking(opts, offset, limit)
+	if err != nil {
+		return entry, err
+	}
+	total, err := repo.Count()
+	if err != nil {
+		return entry, err
+	}
+	entry = rankingCacheEntry{
+		accounts: accounts,
+		total:    total,
+		modified: now,
+		expires:  now.Add(rankingWindowCacheTTL),
+	}
+	rankingCacheMu.Lock()
+	for k, e := range rankingCache {
+		if now.After(e.expires) {
+			delete(rankingCache, k)
+		}
+	}
+	rankingCache[key] = entry
+	rankingCacheMu.Unlock()
+	return entry, nil
+}
+
+// writeCacheHeaders 写入 ETag 和 Last-Modified，客户端缓存仍然有效时返回 304 并返回 true
+func writeCacheHeaders(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if match == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.Truncate(time.Second).After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
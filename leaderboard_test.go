@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeAccountRepo 只实现 All，其余方法未使用
+type fakeAccountRepo struct {
+	AccountRepo
+	accounts []AccountResponse
+}
+
+func (r *fakeAccountRepo) All() ([]AccountResponse, error) {
+	all := make([]AccountResponse, len(r.accounts))
+	copy(all, r.accounts)
+	return all, nil
+}
+
+func leaderboardAddresses(lb *Leaderboard, key string) []string {
+	list, _ := lb.page(key, 0, 100)
+	addresses := make([]string, len(list))
+	for i, a := range list {
+		addresses[i] = a.Address
+	}
+	return addresses
+}
+
+func assertAddresses(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLeaderboardUpdate(t *testing.T) {
+	repo := &fakeAccountRepo{accounts: []AccountResponse{
+		{Address: "0xa", Balance: 10, Cred: 1, ShareNnum: 5},
+		{Address: "0xb", Balance: 20, Cred: 3, ShareNnum: 5},
+		{Address: "0xc", Balance: 10, Cred: 2, ShareNnum: 1},
+	}}
+	lb := &Leaderboard{}
+	if err := lb.reload(repo); err != nil {
+		t.Fatal(err)
+	}
+	// 分数相同时按地址升序
+	assertAddresses(t, leaderboardAddresses(lb, "balance"), "0xb", "0xa", "0xc")
+	assertAddresses(t, leaderboardAddresses(lb, "cred"), "0xb", "0xc", "0xa")
+	assertAddresses(t, leaderboardAddresses(lb, "share_num"), "0xa", "0xb", "0xc")
+
+	_, version, _ := lb.state()
+	lb.update("0xc", 30, 2, 1)
+	assertAddresses(t, leaderboardAddresses(lb, "balance"), "0xc", "0xb", "0xa")
+	assertAddresses(t, leaderboardAddresses(lb, "cred"), "0xb", "0xc", "0xa")
+	if rank := lb.rank("balance", "0xc"); rank != 1 {
+		t.Errorf("rank of 0xc = %d, want 1", rank)
+	}
+	_, next, _ := lb.state()
+	if next != version+1 {
+		t.Errorf("version = %d, want %d", next, version+1)
+	}
+
+	// 没有变化时不改变版本号
+	lb.update("0xc", 30, 2, 1)
+	if _, v, _ := lb.state(); v != next {
+		t.Errorf("version changed on no-op update: %d -> %d", next, v)
+	}
+
+	// 新帐户插入到对应位置
+	lb.update("0xd", 15, 0, 9)
+	assertAddresses(t, leaderboardAddresses(lb, "balance"), "0xc", "0xb", "0xd", "0xa")
+	assertAddresses(t, leaderboardAddresses(lb, "share_num"), "0xd", "0xa", "0xb", "0xc")
+	list, total := lb.page("balance", 1, 2)
+	if total != 4 || len(list) != 2 || list[0].Rank != 2 || list[0].Score != 20 || list[1].Address != "0xd" {
+		t.Errorf("page(1, 2) = %+v, total %d", list, total)
+	}
+	if rank := lb.rank("balance", "0xe"); rank != 0 {
+		t.Errorf("rank of missing account = %d, want 0", rank)
+	}
+}
+
+func TestLeaderboardUpdateBeforeLoad(t *testing.T) {
+	lb := &Leaderboard{}
+	lb.update("0xa", 1, 1, 1)
+	if loaded, version, _ := lb.state(); loaded || version != 0 {
+		t.Errorf("update before reload changed state: loaded %v, version %d", loaded, version)
+	}
+}
+
+func TestLeaderboardReloadKeepsVersion(t *testing.T) {
+	repo := &fakeAccountRepo{accounts: []AccountResponse{{Address: "0xa", Balance: 1}}}
+	lb := &Leaderboard{}
+	if err := lb.reload(repo); err != nil {
+		t.Fatal(err)
+	}
+	_, version, _ := lb.state()
+	if err := lb.reload(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, v, _ := lb.state(); v != version {
+		t.Errorf("reload without changes: version %d -> %d", version, v)
+	}
+
+	repo.accounts[0].Balance = 2
+	if err := lb.reload(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, v, _ := lb.state(); v != version+1 {
+		t.Errorf("reload with changes: version = %d, want %d", v, version+1)
+	}
+}
+
+func TestWriteCacheHeaders(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	etag := `"lb-3-balance-1-10"`
+	tests := []struct {
+		name        string
+		header      string
+		value       string
+		notModified bool
+	}{
+		{"no validators", "", "", false},
+		{"matching etag", "If-None-Match", etag, true},
+		{"stale etag", "If-None-Match", `"lb-2-balance-1-10"`, false},
+		{"not modified since", "If-Modified-Since", modified.Format(http.TimeFormat), true},
+		{"modified since", "If-Modified-Since", modified.Add(-time.Minute).Format(http.TimeFormat), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/accountRanking", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			if got := writeCacheHeaders(w, r, etag, modified); got != tt.notModified {
+				t.Fatalf("writeCacheHeaders = %v, want %v", got, tt.notModified)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q", w.Header().Get("ETag"))
+			}
+			if tt.notModified && w.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", w.Code)
+			}
+		})
+	}
+}
+
+func TestRankingPageETag(t *testing.T) {
+	saved := leaderboard
+	t.Cleanup(func() { leaderboard = saved })
+	leaderboard = &Leaderboard{}
+	if err := leaderboard.reload(&fakeAccountRepo{accounts: []AccountResponse{{Address: "0xa", Balance: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &Server{}
+	opts, err := newRankingOptions("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, etag, _, err := srv.rankingPage(opts, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, same, _, _ := srv.rankingPage(opts, 1, 10)
+	if same != etag {
+		t.Errorf("etag changed without updates: %s -> %s", etag, same)
+	}
+	if _, other, _, _ := srv.rankingPage(opts, 2, 10); other == etag {
+		t.Errorf("different pages share etag %s", etag)
+	}
+
+	leaderboard.update("0xa", 2, 0, 0)
+	_, updated, _, _ := srv.rankingPage(opts, 1, 10)
+	if updated == etag {
+		t.Errorf("etag %s not changed after update", etag)
+	}
+}
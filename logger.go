@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 日志字段名，所有日志行统一使用
+const (
+	logKeyBlockNum  = "block_num"
+	logKeyTxHash    = "tx_hash"
+	logKeyAddress   = "address"
+	logKeyRequestId = "request_id"
+)
+
+var (
+	logger      = slog.Default()
+	auditLogger = slog.Default()
+	logLevel    = new(slog.LevelVar)
+)
+
+type logCtxKey struct{}
+
+type LogLevelInput struct {
+	Level string `json:"level"`
+}
+
+// initLogger 根据 LOG_LEVEL、LOG_FORMAT、LOG_FILE 等配置初始化日志，
+// 控制台和文件同时输出，文件按大小滚动。配置已在启动时校验
+func initLogger() {
+	level, _ := parseLogLevel(cfg.Log.Level)
+	logLevel.Set(level)
+
+	logger = slog.New(newLogHandler(io.MultiWriter(os.Stdout, newRotateWriter(cfg.Log.File)), logLevel))
+	slog.SetDefault(logger)
+
+	// 注册审计记录单独写入 register_server.log
+	auditLogger = slog.New(slog.NewJSONHandler(newRotateWriter(cfg.Log.RegisterAuditFile), nil))
+}
+
+func newLogHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.ToLower(cfg.Log.Format) == "logfmt" {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+func newRotateWriter(filename string) io.Writer {
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAge:     cfg.Log.MaxAgeDays,
+		Compress:   true,
+	}
+}
+
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// logFatal 记录错误日志后退出进程，替代 log.Fatal
+func logFatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+func blockLogger(blockNum int) *slog.Logger {
+	return logger.With(logKeyBlockNum, blockNum)
+}
+
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(logCtxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+func newRequestId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// withRequestLog 为每个请求分配 request id，并将带有该字段的 logger 放入 context
+func withRequestLog(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-Id")
+		if requestId == "" {
+			requestId = newRequestId()
+		}
+		w.Header().Set("X-Request-Id", requestId)
+
+		l := logger.With(logKeyRequestId, requestId)
+		if address := r.URL.Query().Get("address"); address != "" {
+			l = l.With(logKeyAddress, address)
+		}
+		start := time.Now()
+		next(w, r.WithContext(context.WithValue(r.Context(), logCtxKey{}, l)))
+		l.Debug("request handled", "method", r.Method, "path", r.URL.Path, "duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// handleLogLevel 查询或在运行时修改日志级别，修改需要管理员令牌
+func handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeLogLevel(w)
+	case http.MethodPost, http.MethodPut:
+		requireAdmin(setLogLevel)(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
+	}
+}
+
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var input LogLevelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, errCodeBadRequest, "Bad Request")
+		return
+	}
+	level, err := parseLogLevel(input.Level)
+	if err != nil || input.Level == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Invalid log level")
+		return
+	}
+	logLevel.Set(level)
+	loggerFromContext(r.Context()).Info("日志级别已修改", "level", level.String())
+	writeLogLevel(w)
+}
+
+func writeLogLevel(w http.ResponseWriter) {
+	response := Response{
+		Data:    logLevel.Level().String(),
+		Message: "success",
+		Code:    1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// route 一条路由及其接口文档。routes 按这张表注册处理函数，/openapi.json 也由这张表生成，
+// 文档中的路径与注册的路由不一致时启动失败
+type route struct {
+	pattern string // ServeMux 的路由，以 / 结尾时匹配子路径
+	handler http.HandlerFunc
+	ops     []apiOperation
+}
+
+// apiOperation 一个接口的文档。Data 为成功响应中 data 字段类型的零值，由反射生成 schema；
+// Data 中类型为 interface{} 的列表字段（如 QueryList.List）的元素类型由 Items 指定
+type apiOperation struct {
+	Method      string
+	Path        string // OpenAPI 路径，例如 /account/{address}
+	Summary     string
+	Params      []apiParam
+	Body        interface{}
+	Data        interface{}
+	OneOf       []interface{} // data 可能是多种类型之一
+	Items       interface{}
+	Status      int      // 成功状态码，默认 200
+	ContentType []string // 非 JSON 响应的类型
+	Errors      []int
+	Admin       bool
+}
+
+type apiParam struct {
+	Name        string
+	In          string // 默认 query
+	Type        string // 默认 string
+	Required    bool
+	Description string
+	Enum        []string
+}
+
+// 常用参数
+var (
+	pageParams = []apiParam{
+		{Name: "page", Type: "integer", Description: "页码，从 1 开始"},
+		{Name: "pagesize", Type: "integer", Description: "每页条数，默认 10，最大 100"},
+	}
+	transactionFilterParams = []apiParam{
+		{Name: "address", Description: "发送方，同 from"},
+		{Name: "from", Description: "发送方"},
+		{Name: "to", Description: "接收方"},
+		{Name: "party", Description: "发送方或接收方"},
+		{Name: "method", Description: "合约方法名或 method_id"},
+		{Name: "person", Type: "integer", Description: "person_id"},
+		{Name: "status", Type: "integer", Description: "交易状态"},
+		{Name: "block_start", Type: "integer", Description: "起始区块（含）"},
+		{Name: "block_end", Type: "integer", Description: "结束区块（含）"},
+		{Name: "start", Type: "integer", Description: "import_time 起始毫秒时间戳（含）"},
+		{Name: "end", Type: "integer", Description: "import_time 结束毫秒时间戳（不含）"},
+		{Name: "order", Enum: []string{"desc", "asc"}},
+	}
+	transactionPageParams = append(append(append([]apiParam{}, transactionFilterParams...), pageParams...),
+		apiParam{Name: "cursor", Description: "游标分页，第一页传空值"},
+		apiParam{Name: "with_total", Type: "boolean", Description: "游标分页时是否返回 total"},
+	)
+	exportParams = append(append([]apiParam{}, transactionFilterParams...),
+		apiParam{Name: "format", Enum: []string{"csv", "ndjson"}},
+		apiParam{Name: "gzip", Type: "boolean"},
+	)
+	rankingParams = []apiParam{
+		{Name: "sort", Enum: []string{"balance", "cred", "share_num"}},
+		{Name: "window", Enum: []string{"all", "7d", "30d"}},
+	}
+)
+
+var pathParamPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+// validateRoutes 检查每条路由都有文档，且文档路径属于该路由
+func validateRoutes(table []route) error {
+	for _, rt := range table {
+		if len(rt.ops) == 0 {
+			return fmt.Errorf("route %s has no api documentation", rt.pattern)
+		}
+		for _, op := range rt.ops {
+			path := pathParamPattern.ReplaceAllString(op.Path, "x")
+			if strings.HasSuffix(rt.pattern, "/") {
+				if !strings.HasPrefix(path, rt.pattern) || path == rt.pattern {
+					return fmt.Errorf("api path %s does not match route %s", op.Path, rt.pattern)
+				}
+			} else if path != rt.pattern {
+				return fmt.Errorf("api path %s does not match route %s", op.Path, rt.pattern)
+			}
+			for _, name := range pathParamPattern.FindAllString(op.Path, -1) {
+				if !hasPathParam(op.Params, strings.Trim(name, "{}")) {
+					return fmt.Errorf("api path %s: path parameter %s is not documented", op.Path, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func hasPathParam(params []apiParam, name string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == "path" {
+			return true
+		}
+	}
+	return false
+}
+
+// openAPIBuilder 生成 OpenAPI 3 文档，结构体类型放在 components.schemas 中按类型名引用
+type openAPIBuilder struct {
+	schemas map[string]interface{}
+}
+
+// buildOpenAPI 由路由表生成 OpenAPI 3.0 文档
+func buildOpenAPI(table []route) map[string]interface{} {
+	b := &openAPIBuilder{schemas: make(map[string]interface{})}
+	b.schemas["ErrorResponse"] = b.structSchema(reflect.TypeOf(ErrorResponse{}), nil)
+
+	paths := make(map[string]map[string]interface{})
+	for _, rt := range table {
+		for _, op := range rt.ops {
+			if paths[op.Path] == nil {
+				paths[op.Path] = make(map[string]interface{})
+			}
+			paths[op.Path][strings.ToLower(op.Method)] = b.operation(op)
+		}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "bc-go-service",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func (b *openAPIBuilder) operation(op apiOperation) map[string]interface{} {
+	o := map[string]interface{}{"summary": op.Summary}
+	params := make([]interface{}, 0, len(op.Params))
+	for _, p := range op.Params {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		schema := map[string]interface{}{"type": typ}
+		if len(p.Enum) > 0 {
+			schema["enum"] = p.Enum
+		}
+		param := map[string]interface{}{"name": p.Name, "in": in, "schema": schema, "required": p.Required || in == "path"}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+	if op.Body != nil {
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": b.schemaOf(reflect.TypeOf(op.Body))}},
+		}
+	}
+	if op.Admin {
+		o["security"] = []interface{}{map[string]interface{}{"adminToken": []string{}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := make(map[string]interface{})
+	if len(op.ContentType) > 0 {
+		content := make(map[string]interface{})
+		for _, ct := range op.ContentType {
+			content[ct] = map[string]interface{}{"schema": map[string]interface{}{}}
+		}
+		responses[fmt.Sprint(status)] = map[string]interface{}{"description": http.StatusText(status), "content": content}
+	} else {
+		envelope := map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code": map[string]interface{}{"type": "integer", "enum": []int{1}},
+				"msg":  map[string]interface{}{"type": "string"},
+				"data": b.dataSchema(op),
+			},
+		}
+		responses[fmt.Sprint(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": envelope}},
+		}
+	}
+	errs := append([]int{http.StatusInternalServerError}, op.Errors...)
+	if op.Admin {
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	sort.Ints(errs)
+	for _, code := range errs {
+		responses[fmt.Sprint(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"},
+			}},
+		}
+	}
+	o["responses"] = responses
+	return o
+}
+
+func (b *openAPIBuilder) dataSchema(op apiOperation) interface{} {
+	if len(op.OneOf) > 0 {
+		oneOf := make([]interface{}, 0, len(op.OneOf))
+		for _, v := range op.OneOf {
+			oneOf = append(oneOf, b.schemaOf(reflect.TypeOf(v)))
+		}
+		return map[string]interface{}{"oneOf": oneOf}
+	}
+	if op.Data == nil {
+		return map[string]interface{}{}
+	}
+	t := reflect.TypeOf(op.Data)
+	if op.Items != nil {
+		// 列表外层按 Items 展开，不放入 components
+		return b.structSchema(t, reflect.TypeOf(op.Items))
+	}
+	return b.schemaOf(t)
+}
+
+// schemaOf 按 JSON 序列化规则生成类型的 schema
+func (b *openAPIBuilder) schemaOf(t reflect.Type) interface{} {
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaOf(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if name == "" {
+			return b.structSchema(t, nil)
+		}
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = nil // 先占位，避免递归类型无限展开
+			b.schemas[name] = b.structSchema(t, nil)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema 生成结构体的 object schema，匿名嵌入的结构体字段展开到外层；
+// items 不为空时 interface{} 类型的字段按 items 数组生成
+func (b *openAPIBuilder) structSchema(t reflect.Type, items reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	b.addFields(t, items, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func (b *openAPIBuilder) addFields(t reflect.Type, items reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			b.addFields(f.Type, items, properties)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if items != nil && f.Type.Kind() == reflect.Interface {
+			properties[name] = map[string]interface{}{"type": "array", "items": b.schemaOf(items)}
+			continue
+		}
+		properties[name] = b.schemaOf(f.Type)
+	}
+}
+
+// serveOpenAPI 返回启动时生成的 OpenAPI 文档
+func serveOpenAPI(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}
+}
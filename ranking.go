@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 排行榜排序字段
+var rankingSortKeys = map[string]bool{
+	"balance":   true,
+	"cred":      true,
+	"share_num": true,
+}
+
+// 排行榜时间窗口
+var rankingWindows = map[string]time.Duration{
+	"all": 0,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+type RankingOptions struct {
+	Sort   string
+	Window string
+}
+
+type AccountRankResponse struct {
+	Account    AccountResponse   `json:"account"`
+	Sort       string            `json:"sort"`
+	Window     string            `json:"window"`
+	Total      int               `json:"total"`
+	Neighbours []AccountResponse `json:"neighbours"`
+}
+
+// parseRankingOptions 解析 sort（balance、cred、share_num）和 window（all、7d、30d）参数
+func parseRankingOptions(r *http.Request) (RankingOptions, error) {
+	return newRankingOptions(r.URL.Query().Get("sort"), r.URL.Query().Get("window"))
+}
+
+// rankingQuery 返回排行榜的数据集 (address, balance, cred, share_num, score)。
+// 全部时间按当前值排序；7d/30d 时 share_num 按窗口内成功的 shareData 交易数，
+// balance/cred 按窗口内 bc_account_history 记录的增量排序
+func rankingQuery(opts RankingOptions) (string, []interface{}) {
+	window := rankingWindows[opts.Window]
+	if window == 0 {
+		return "SELECT address, balance, cred, share_num, " + opts.Sort + " AS score FROM bc_block_account", nil
+	}
+	since := time.Now().Add(-window).UnixMilli()
+	if opts.Sort == "share_num" {
+		return "SELECT a.address, a.balance, a.cred, a.share_num, COALESCE(t.cnt, 0) AS score FROM bc_block_account a " +
+			"LEFT JOIN (SELECT `from` AS address, COUNT(*) AS cnt FROM bc_block_transactions WHERE method_id = ? AND `status` = 0 AND import_time >= ? GROUP BY `from`) t " +
+			"ON t.address = a.address", []interface{}{contractMethodId, since}
+	}
+	return "SELECT a.address, a.balance, a.cred, a.share_num, COALESCE(h.delta, 0) AS score FROM bc_block_account a " +
+		"LEFT JOIN (SELECT address, SUM(new_" + opts.Sort + " - old_" + opts.Sort + ") AS delta FROM bc_account_history WHERE created_at >= ? GROUP BY address) h " +
+		"ON h.address = a.address", []interface{}{since}
+}
+
+func neighbourOffset(rank int, neighbours int) int {
+	if rank-1-neighbours < 0 {
+		return 0
+	}
+	return rank - 1 - neighbours
+}
+
+func (srv *Server) accountRanking(w http.ResponseWriter, r *http.Request) {
+	// 获取请求参数
+	queryValues := r.URL.Query()
+	page, _ := strconv.Atoi(queryValues.Get("page"))
+	pageSize, _ := strconv.Atoi(queryValues.Get("pagesize"))
+	opts, err := parseRankingOptions(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	result, etag, modified, err := srv.rankingPage(opts, page, pageSize)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if writeCacheHeaders(w, r, etag, modified) {
+		return
+	}
+
+	// 构建 Response 结构体
+	response := ResponseList{
+		Data: result,
+		Msg:  "success",
+		Code: 1,
+	}
+
+	// 序列化 Response 结构为 JSON 字符串
+	responseJsonData, err := json.Marshal(response)
+	if err != nil {
+		loggerFromContext(r.Context()).Error("Error serializing JSON data", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Error serializing JSON data")
+		return
+	}
+
+	// 设置响应头为 JSON 格式
+	w.Header().Set("Content-Type", "application/json")
+
+	// 发送响应
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJsonData)
+}
+
+// accountRankByAddress 处理 /accountRanking/{address}，返回该帐户的名次和前后 neighbours（默认 2）名帐户
+func (srv *Server) accountRankByAddress(w http.ResponseWriter, r *http.Request) {
+	address := strings.TrimPrefix(r.URL.Path, "/accountRanking/")
+	if !isValidAddress(address) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidAddress, "Invalid address format")
+		return
+	}
+	opts, err := parseRankingOptions(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	neighbours, err := strconv.Atoi(r.URL.Query().Get("neighbours"))
+	if err != nil {
+		neighbours = -1
+	}
+
+	result, err := srv.accountRank(opts, address, neighbours)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := ResponseList{
+		Data: result,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
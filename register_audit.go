@@ -0,0 +1,293 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	txHashPattern   = regexp.MustCompile(`transaction hash:\s*(0x[0-9a-fA-F]{64})`)
+	txStatusPattern = regexp.MustCompile(`transaction status:\s*(\d+)`)
+)
+
+// RegisterAudit 一次注册请求的审计记录，对应 bc_register_audit 表
+type RegisterAudit struct {
+	Id            int64  `json:"id"`
+	Address       string `json:"address"`
+	Caller        string `json:"caller"`
+	RemoteIP      string `json:"remote_ip"`
+	RequestId     string `json:"request_id"`
+	TxHash        string `json:"tx_hash"`
+	ReceiptStatus int    `json:"receipt_status"`
+	Result        string `json:"result"`
+	Error         string `json:"error"`
+	CreatedAt     int64  `json:"created_at"`
+	FinishedAt    int64  `json:"finished_at"`
+}
+
+// RegisterStatus 某个地址的注册状态，Result 为最近一次尝试的结果。
+// 公开接口不返回调用方、IP 等审计字段，完整记录见 /admin/registrations
+type RegisterStatus struct {
+	Registered   bool   `json:"registered"`
+	RegisteredAt int64  `json:"registered_at"`
+	TxHash       string `json:"tx_hash"`
+	Result       string `json:"result"`
+}
+
+// RegisterResult 控制台调用合约的结果
+type RegisterResult struct {
+	Command       string
+	Output        string
+	TxHash        string
+	ReceiptStatus int
+	Message       string
+	Success       bool
+	Err           error
+}
+
+// runRegisterCommand 通过控制台调用 Cred 合约的 register 方法
+func runRegisterCommand(address string) RegisterResult {
+	result := runConsoleCall("register", address)
+	if result.Err != nil {
+		return result
+	}
+
+	if strings.Contains(result.Output, "transaction status: 0") {
+		result.Message = "Authorization successful"
+		result.Success = true
+	} else if strings.Contains(result.Output, "account already has role") {
+		result.Message = "Account already authorized"
+		result.Success = true
+	} else {
+		result.Message = "Authorization failed"
+	}
+	return result
+}
+
+// runConsoleCall 通过控制台调用 Cred 合约的 method(address)，解析输出中的交易哈希和回执状态
+func runConsoleCall(method string, address string) RegisterResult {
+	result := RegisterResult{ReceiptStatus: -1}
+	result.Command = fmt.Sprintf("echo call Cred %s %s %s | bash ./console/start.sh", contractAddress, method, address)
+	cmd := exec.Command("bash", "-c", result.Command)
+	output, err := cmd.CombinedOutput() // 获取命令的标准输出和标准错误输出
+	result.Output = string(output)
+	if err != nil {
+		result.Message = "error"
+		result.Err = err
+		return result
+	}
+
+	if m := txHashPattern.FindStringSubmatch(result.Output); m != nil {
+		result.TxHash = m[1]
+	}
+	if m := txStatusPattern.FindStringSubmatch(result.Output); m != nil {
+		result.ReceiptStatus, _ = strconv.Atoi(m[1])
+	}
+	return result
+}
+
+// callerIdentity 调用方身份，优先取 Basic Auth 用户名，其次取 X-Caller-Id 请求头
+func callerIdentity(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	return r.Header.Get("X-Caller-Id")
+}
+
+// requireAdmin 校验 ADMIN_TOKEN，未配置时拒绝所有管理接口请求
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *SQL) insertRegisterAudit(audit *RegisterAudit) error {
+	var err error
+	audit.Id, _, err = s.Insert("INSERT INTO bc_register_audit (address, caller, remote_ip, request_id, created_at) VALUES (?, ?, ?, ?, ?)",
+		audit.Address, audit.Caller, audit.RemoteIP, audit.RequestId, audit.CreatedAt)
+	return err
+}
+
+// finishRegisterAudit 写入执行结果，同时在审计日志文件中保留一条结构化记录
+func (s *SQL) finishRegisterAudit(audit *RegisterAudit, result RegisterResult) {
+	audit.TxHash = result.TxHash
+	audit.ReceiptStatus = result.ReceiptStatus
+	audit.Result = result.Message
+	if result.Err != nil {
+		audit.Error = result.Err.Error()
+	}
+	audit.FinishedAt = time.Now().UnixMilli()
+
+	auditLogger.Info("register",
+		logKeyRequestId, audit.RequestId,
+		logKeyAddress, audit.Address,
+		logKeyTxHash, audit.TxHash,
+		"caller", audit.Caller,
+		"remote_ip", audit.RemoteIP,
+		"command", result.Command,
+		"output", result.Output,
+		"receipt_status", audit.ReceiptStatus,
+		"result", audit.Result,
+		"error", audit.Error,
+		"duration_ms", audit.FinishedAt-audit.CreatedAt,
+	)
+
+	if audit.Id == 0 {
+		return
+	}
+	_, err := s.Exec("UPDATE bc_register_audit SET tx_hash = ?, receipt_status = ?, result = ?, error = ?, finished_at = ? WHERE id = ?",
+		audit.TxHash, audit.ReceiptStatus, audit.Result, audit.Error, audit.FinishedAt, audit.Id)
+	if err != nil {
+		logger.Error("更新注册审计失败", logKeyAddress, audit.Address, "error", err)
+	}
+}
+
+const registerAuditColumns = "id, address, caller, remote_ip, request_id, tx_hash, receipt_status, result, COALESCE(error, ''), created_at, finished_at"
+
+func scanRegisterAudits(rows *sql.Rows) ([]RegisterAudit, error) {
+	audits := make([]RegisterAudit, 0)
+	for rows.Next() {
+		a := RegisterAudit{}
+		err := rows.Scan(&a.Id, &a.Address, &a.Caller, &a.RemoteIP, &a.RequestId, &a.TxHash, &a.ReceiptStatus, &a.Result, &a.Error, &a.CreatedAt, &a.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		audits = append(audits, a)
+	}
+	return audits, rows.Err()
+}
+
+// getRegisterStatus 按 address 或 tx_hash 查询注册状态，返回最近一次成功记录和最近一次尝试的结果
+func (srv *Server) getRegisterStatus(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	address := strings.ToLower(queryValues.Get("address"))
+	txHash := queryValues.Get("tx_hash")
+	if address == "" && txHash == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "address or tx_hash is required")
+		return
+	}
+
+	s := srv.sql
+
+	query := "SELECT " + registerAuditColumns + " FROM bc_register_audit WHERE address = ? ORDER BY id DESC LIMIT 100"
+	arg := address
+	if address == "" {
+		query = "SELECT " + registerAuditColumns + " FROM bc_register_audit WHERE tx_hash = ? ORDER BY id DESC LIMIT 100"
+		arg = txHash
+	}
+	rows, err := s.Query(query, arg)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	defer rows.Close()
+	attempts, err := scanRegisterAudits(rows)
+	if err != nil {
+		writeDatabaseError(w, r, "Error scanning row data", err)
+		return
+	}
+
+	status := RegisterStatus{Registered: false}
+	if len(attempts) > 0 {
+		status.Result = attempts[0].Result
+	}
+	for i := range attempts {
+		if attempts[i].Result == "Authorization successful" || attempts[i].Result == "Account already authorized" {
+			status.Registered = true
+			status.RegisteredAt = attempts[i].FinishedAt
+			status.TxHash = attempts[i].TxHash
+			break
+		}
+	}
+
+	response := ResponseList{
+		Data: status,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// listRegistrations 管理端注册记录列表，支持 address、caller、result、tx_hash 以及 start/end 时间（毫秒）过滤
+func (srv *Server) listRegistrations(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	page, err := strconv.Atoi(queryValues.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(queryValues.Get("pagesize"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, field := range []string{"address", "caller", "result", "tx_hash"} {
+		if v := queryValues.Get(field); v != "" {
+			where = append(where, field+" = ?")
+			args = append(args, v)
+		}
+	}
+	if v, err := strconv.ParseInt(queryValues.Get("start"), 10, 64); err == nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, v)
+	}
+	if v, err := strconv.ParseInt(queryValues.Get("end"), 10, 64); err == nil {
+		where = append(where, "created_at < ?")
+		args = append(args, v)
+	}
+	whereSql := ""
+	if len(where) > 0 {
+		whereSql = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	s := srv.sql
+
+	offset := (page - 1) * pageSize
+	rows, err := s.Query("SELECT "+registerAuditColumns+" FROM bc_register_audit"+whereSql+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, pageSize, offset)...)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	defer rows.Close()
+	audits, err := scanRegisterAudits(rows)
+	if err != nil {
+		writeDatabaseError(w, r, "Error scanning row data", err)
+		return
+	}
+
+	var total int
+	err = s.QueryRow("SELECT COUNT(*) FROM bc_register_audit"+whereSql, args...).Scan(&total)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying total count", err)
+		return
+	}
+
+	response := ResponseList{
+		Data: QueryList{
+			List:     audits,
+			Page:     page,
+			PageSize: pageSize,
+			Total:    total,
+		},
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const registerBatchMaxSize = 1000
+
+// 批量注册中每个地址的处理结果
+const (
+	batchResultQueued            = "queued"
+	batchResultCoalesced         = "coalesced"
+	batchResultInvalid           = "invalid_address"
+	batchResultDuplicate         = "duplicate"
+	batchResultAlreadyRegistered = "already_registered"
+	batchResultError             = "error"
+)
+
+type BatchRegisterInput struct {
+	Addresses   []string `json:"addresses"`
+	CallbackUrl string   `json:"callback_url"`
+}
+
+type BatchRegisterResult struct {
+	Address string `json:"address"`
+	Result  string `json:"result"`
+	JobId   int64  `json:"job_id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BatchRegisterReport struct {
+	Total   int                   `json:"total"`
+	Summary map[string]int        `json:"summary"`
+	Results []BatchRegisterResult `json:"results"`
+}
+
+// parseBatchAddresses 解析请求中的地址列表，支持 JSON（数组或 {"addresses": [...]}）、
+// text/csv 请求体以及 multipart 上传的 CSV 文件（字段名 file），CSV 取第一列
+func parseBatchAddresses(r *http.Request) (addresses []string, callbackUrl string, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		addresses, err = parseCSVAddresses(file)
+		return addresses, r.FormValue("callback_url"), err
+	case "text/csv":
+		addresses, err = parseCSVAddresses(r.Body)
+		return addresses, r.URL.Query().Get("callback_url"), err
+	default:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		if err = json.Unmarshal(body, &addresses); err == nil {
+			return addresses, r.URL.Query().Get("callback_url"), nil
+		}
+		var input BatchRegisterInput
+		if err = json.Unmarshal(body, &input); err != nil {
+			return nil, "", err
+		}
+		return input.Addresses, input.CallbackUrl, nil
+	}
+}
+
+func parseCSVAddresses(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	addresses := make([]string, 0)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		value := strings.TrimSpace(record[0])
+		// 跳过表头
+		if i == 0 && strings.EqualFold(value, "address") {
+			continue
+		}
+		if value != "" {
+			addresses = append(addresses, value)
+		}
+	}
+	return addresses, nil
+}
+
+// registeredAddresses 返回已存在于 bc_block_account 中的地址（小写）
+func (s *SQL) registeredAddresses(addresses []string) (map[string]bool, error) {
+	registered := make(map[string]bool)
+	for start := 0; start < len(addresses); start += 200 {
+		end := start + 200
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunk := addresses[start:end]
+		args := make([]interface{}, len(chunk))
+		for i, address := range chunk {
+			args[i] = address
+		}
+		rows, err := s.Query("SELECT address FROM bc_block_account WHERE address IN (?"+strings.Repeat(", ?", len(chunk)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var address string
+			if err := rows.Scan(&address); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			registered[strings.ToLower(address)] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return registered, nil
+}
+
+// handleBatchRegister 批量提交注册任务，逐个地址返回处理结果
+func (srv *Server) handleBatchRegister(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	addresses, callbackUrl, err := parseBatchAddresses(r)
+	if err != nil {
+		l.Warn("Failed to parse request body", "error", err)
+		writeError(w, http.StatusBadRequest, errCodeBadRequest, "Bad Request")
+		return
+	}
+	if len(addresses) == 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "No addresses")
+		return
+	}
+	if len(addresses) > registerBatchMaxSize {
+		writeError(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, fmt.Sprintf("Too many addresses, max %d", registerBatchMaxSize))
+		return
+	}
+	if callbackUrl != "" {
+		if err := checkCallbackUrl(callbackUrl); err != nil {
+			writeError(w, http.StatusBadRequest, errCodeInvalidParameter, err.Error())
+			return
+		}
+	}
+
+	s := srv.sql
+
+	// 校验格式并去重
+	results := make([]BatchRegisterResult, len(addresses))
+	seen := make(map[string]bool)
+	candidates := make([]string, 0, len(addresses))
+	for i, address := range addresses {
+		results[i].Address = address
+		if !isValidAddress(address) {
+			results[i].Result = batchResultInvalid
+			continue
+		}
+		key := strings.ToLower(address)
+		if seen[key] {
+			results[i].Result = batchResultDuplicate
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, key)
+	}
+
+	registered, err := s.registeredAddresses(candidates)
+	if err != nil {
+		l.Error("Error querying database", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		return
+	}
+
+	// 以有限并发提交注册任务
+	concurrency := cfg.Register.BatchConcurrency
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Result != "" {
+			continue
+		}
+		if registered[strings.ToLower(results[i].Address)] {
+			results[i].Result = batchResultAlreadyRegistered
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(result *BatchRegisterResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			job := &RegisterJob{
+				Address:     result.Address,
+				CallbackUrl: callbackUrl,
+				Caller:      callerIdentity(r),
+				RemoteIP:    r.RemoteAddr,
+				RequestId:   w.Header().Get("X-Request-Id"),
+			}
+			coalesced, err := s.enqueueRegisterJob(job)
+			if err != nil {
+				l.Error("Error enqueueing register job", logKeyAddress, result.Address, "error", err)
+				result.Result = batchResultError
+				result.Error = "Internal Server Error"
+				return
+			}
+			result.Result = batchResultQueued
+			if coalesced {
+				result.Result = batchResultCoalesced
+			}
+			result.JobId = job.Id
+			result.Status = job.Status
+		}(&results[i])
+	}
+	wg.Wait()
+
+	report := BatchRegisterReport{
+		Total:   len(results),
+		Summary: make(map[string]int),
+		Results: results,
+	}
+	for _, result := range results {
+		report.Summary[result.Result]++
+	}
+	l.Info("批量提交注册任务", "total", report.Total, "summary", report.Summary)
+
+	jsonResponse, err := json.Marshal(ResponseList{
+		Data: report,
+		Msg:  "accepted",
+		Code: 1,
+	})
+	if err != nil {
+		l.Error("Failed to marshal JSON response", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(jsonResponse)
+}
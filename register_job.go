@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	registerJobPending   = "pending"
+	registerJobRunning   = "running"
+	registerJobSucceeded = "succeeded"
+	registerJobFailed    = "failed"
+)
+
+// 运行中超过该时长未完成的任务视为 worker 异常退出，重新放回队列
+const registerJobStaleAfter = 10 * time.Minute
+
+// 回调请求的签名头，X-Bc-Signature 为 "sha256=" 加上以 REGISTER_WEBHOOK_SECRET 为密钥
+// 对 "X-Bc-Timestamp 的值.请求体" 计算的 HMAC-SHA256（十六进制）
+const (
+	webhookTimestampHeader = "X-Bc-Timestamp"
+	webhookSignatureHeader = "X-Bc-Signature"
+)
+
+// webhookClient 只连接公网地址。连接时检查实际拨号的 IP，DNS 重新绑定和重定向也无法访问内网
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// 不在 net.IP 判断范围内的非公网网段：0.0.0.0/8，运营商级 NAT 100.64.0.0/10（部分云服务的元数据地址在此网段）
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// RegisterJob 注册任务，对应 bc_register_job 表。
+// 同一地址同时只会存在一个 pending/running 任务，重复提交返回已有任务。
+// 任务 id 是连续的，回调地址和调用方信息不出现在接口响应和回调请求中，只记录在注册审计里
+type RegisterJob struct {
+	Id             int64  `json:"id"`
+	Address        string `json:"address"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	MaxAttempts    int    `json:"max_attempts"`
+	NextRunAt      int64  `json:"next_run_at"`
+	TxHash         string `json:"tx_hash"`
+	ReceiptStatus  int    `json:"receipt_status"`
+	Result         string `json:"result"`
+	Error          string `json:"error"`
+	CallbackUrl    string `json:"-"`
+	CallbackStatus int    `json:"callback_status"`
+	Caller         string `json:"-"`
+	RemoteIP       string `json:"-"`
+	RequestId      string `json:"-"`
+	CreatedAt      int64  `json:"created_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+	FinishedAt     int64  `json:"finished_at"`
+}
+
+func initRegisterJobTask(s *SQL) {
+	workers := cfg.Register.Workers
+	for i := 0; i < workers; i++ {
+		go executeRegisterJobTask(s, fmt.Sprintf("%s-%d-%d", hostname(), os.Getpid(), i))
+	}
+	go executeRegisterJobRecoverTask(s)
+	logger.Info("注册任务加载完成!", "workers", workers)
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "bc_server"
+	}
+	return name
+}
+
+func executeRegisterJobTask(s *SQL, workerId string) {
+	ticker := time.NewTicker(1 * time.Second)
+	for {
+		<-ticker.C // 等待计时器触发
+		for {
+			job, err := s.claimRegisterJob(workerId)
+			if err != nil {
+				logger.Error("领取注册任务失败", "worker", workerId, "error", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			s.processRegisterJob(job)
+		}
+	}
+}
+
+func executeRegisterJobRecoverTask(s *SQL) {
+	ticker := time.NewTicker(1 * time.Minute)
+	for {
+		<-ticker.C // 等待计时器触发
+		now := time.Now().UnixMilli()
+		rs, err := s.Exec("UPDATE bc_register_job SET status = ?, locked_by = '', updated_at = ? WHERE status = ? AND locked_at < ?",
+			registerJobPending, now, registerJobRunning, now-registerJobStaleAfter.Milliseconds())
+		if err != nil {
+			logger.Error("恢复超时注册任务失败", "error", err)
+			continue
+		}
+		if n, _ := rs.RowsAffected(); n > 0 {
+			logger.Warn("恢复超时注册任务", "count", n)
+		}
+	}
+}
+
+const registerJobColumns = "id, address, status, attempts, max_attempts, next_run_at, tx_hash, receipt_status, result, COALESCE(error, ''), callback_url, callback_status, caller, remote_ip, request_id, created_at, updated_at, finished_at"
+
+func scanRegisterJob(row interface{ Scan(...any) error }) (*RegisterJob, error) {
+	j := &RegisterJob{}
+	err := row.Scan(&j.Id, &j.Address, &j.Status, &j.Attempts, &j.MaxAttempts, &j.NextRunAt, &j.TxHash, &j.ReceiptStatus, &j.Result, &j.Error,
+		&j.CallbackUrl, &j.CallbackStatus, &j.Caller, &j.RemoteIP, &j.RequestId, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (s *SQL) getRegisterJob(id int64) (*RegisterJob, error) {
+	job, err := scanRegisterJob(s.QueryRow("SELECT "+registerJobColumns+" FROM bc_register_job WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// enqueueRegisterJob 提交注册任务，地址已有未完成任务时返回该任务，coalesced 为 true
+func (s *SQL) enqueueRegisterJob(job *RegisterJob) (coalesced bool, err error) {
+	now := time.Now().UnixMilli()
+	job.Address = strings.ToLower(job.Address)
+	job.Status = registerJobPending
+	job.ReceiptStatus = -1
+	job.MaxAttempts = cfg.Register.JobMaxAttempts
+	job.NextRunAt = now
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	for retried := false; ; retried = true {
+		// 地址已有未完成的任务时 active_address 唯一性冲突，插入被忽略
+		id, inserted, err := s.InsertIgnore("INSERT INTO bc_register_job (address, active_address, status, max_attempts, next_run_at, callback_url, caller, remote_ip, request_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			job.Address, job.Address, job.Status, job.MaxAttempts, job.NextRunAt, job.CallbackUrl, job.Caller, job.RemoteIP, job.RequestId, job.CreatedAt, job.UpdatedAt)
+		if err != nil {
+			return false, err
+		}
+		if inserted {
+			job.Id = id
+			return false, nil
+		}
+		existing, err := scanRegisterJob(s.QueryRow("SELECT "+registerJobColumns+" FROM bc_register_job WHERE active_address = ?", job.Address))
+		// 冲突的任务在插入和查询之间已经结束并清空了 active_address，重新插入一次
+		if errors.Is(err, sql.ErrNoRows) && !retried {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		*job = *existing
+		return true, nil
+	}
+}
+
+// claimRegisterJob 领取一个到期的 pending 任务：先查出候选任务，再带 status 条件更新，
+// 其它 worker 抢先领取时更新不到数据，换下一个候选任务
+func (s *SQL) claimRegisterJob(workerId string) (*RegisterJob, error) {
+	now := time.Now().UnixMilli()
+	token := workerId + "-" + newRequestId()
+	for i := 0; i < 3; i++ {
+		var id int64
+		err := s.QueryRow("SELECT id FROM bc_register_job WHERE status = ? AND next_run_at <= ? ORDER BY id LIMIT 1",
+			registerJobPending, now).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rs, err := s.Exec("UPDATE bc_register_job SET status = ?, locked_by = ?, locked_at = ?, attempts = attempts + 1, updated_at = ? WHERE id = ? AND status = ?",
+			registerJobRunning, token, now, now, id, registerJobPending)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := rs.RowsAffected(); n > 0 {
+			return scanRegisterJob(s.QueryRow("SELECT "+registerJobColumns+" FROM bc_register_job WHERE locked_by = ?", token))
+		}
+	}
+	return nil, nil
+}
+
+// processRegisterJob 执行一次注册尝试：未提交过的任务先通过控制台提交，再等待交易回执
+func (s *SQL) processRegisterJob(job *RegisterJob) {
+	l := logger.With(logKeyAddress, job.Address, "job_id", job.Id, "attempt", job.Attempts)
+	audit := &RegisterAudit{
+		Address:   job.Address,
+		Caller:    job.Caller,
+		RemoteIP:  job.RemoteIP,
+		RequestId: job.RequestId,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if err := s.insertRegisterAudit(audit); err != nil {
+		l.Error("记录注册审计失败", "error", err)
+	}
+
+	result := RegisterResult{TxHash: job.TxHash, ReceiptStatus: -1}
+	if job.TxHash == "" {
+		result = runRegisterCommand(job.Address)
+		if result.Err != nil {
+			s.finishRegisterAudit(audit, result)
+			s.retryRegisterJob(job, result.Err)
+			return
+		}
+		job.TxHash = result.TxHash
+		// 控制台已等待回执或帐户已有角色，结果即为最终结果
+		if result.Success {
+			s.finishRegisterAudit(audit, result)
+			s.completeRegisterJob(job, registerJobSucceeded, result)
+			return
+		}
+		if result.TxHash == "" || result.ReceiptStatus >= 0 {
+			s.finishRegisterAudit(audit, result)
+			s.completeRegisterJob(job, registerJobFailed, result)
+			return
+		}
+	}
+
+	// 交易已提交但尚未确认回执（控制台未输出状态，或上一次尝试等待回执失败）
+	l = l.With(logKeyTxHash, job.TxHash)
+	receipt, err := awaitTransactionReceipt(job.TxHash)
+	if err != nil {
+		result.Err = err
+		result.Message = "error"
+		s.finishRegisterAudit(audit, result)
+		s.retryRegisterJob(job, err)
+		return
+	}
+	result.ReceiptStatus = receipt.Status
+	status := registerJobSucceeded
+	if receipt.Status == 0 {
+		result.Message = "Authorization successful"
+		result.Success = true
+	} else {
+		result.Message = "Authorization failed"
+		result.Success = false
+		status = registerJobFailed
+	}
+	s.finishRegisterAudit(audit, result)
+	s.completeRegisterJob(job, status, result)
+	l.Info("注册任务完成", "status", status, "receipt_status", receipt.Status)
+}
+
+// awaitTransactionReceipt 轮询交易回执，超时未上链返回错误
+func awaitTransactionReceipt(hash string) (*TransactionReceipt, error) {
+	var lastErr error
+	for i := 0; i < 10; i++ {
+		receipt, err := getTransactionReceipt(hash)
+		if err == nil && receipt != nil {
+			return receipt, nil
+		}
+		lastErr = err
+		time.Sleep(1 * time.Second)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("receipt of %s not available", hash)
+	}
+	return nil, lastErr
+}
+
+func (s *SQL) retryRegisterJob(job *RegisterJob, cause error) {
+	l := logger.With(logKeyAddress, job.Address, "job_id", job.Id, "attempt", job.Attempts)
+	if job.Attempts >= job.MaxAttempts {
+		l.Error("注册任务重试次数已用完", "error", cause)
+		s.completeRegisterJob(job, registerJobFailed, RegisterResult{TxHash: job.TxHash, ReceiptStatus: -1, Message: "error", Err: cause})
+		return
+	}
+	// 指数退避：5s、10s、20s ...，最长 10 分钟
+	backoff := 5 * time.Second << (job.Attempts - 1)
+	if backoff > 10*time.Minute {
+		backoff = 10 * time.Minute
+	}
+	now := time.Now().UnixMilli()
+	job.Status = registerJobPending
+	job.Error = cause.Error()
+	job.NextRunAt = now + backoff.Milliseconds()
+	job.UpdatedAt = now
+	_, err := s.Exec("UPDATE bc_register_job SET status = ?, tx_hash = ?, error = ?, next_run_at = ?, locked_by = '', updated_at = ? WHERE id = ?",
+		job.Status, job.TxHash, job.Error, job.NextRunAt, job.UpdatedAt, job.Id)
+	if err != nil {
+		l.Error("更新注册任务失败", "error", err)
+		return
+	}
+	l.Warn("注册任务稍后重试", "error", cause, "backoff", backoff.String())
+}
+
+func (s *SQL) completeRegisterJob(job *RegisterJob, status string, result RegisterResult) {
+	now := time.Now().UnixMilli()
+	job.Status = status
+	job.TxHash = result.TxHash
+	job.ReceiptStatus = result.ReceiptStatus
+	job.Result = result.Message
+	job.Error = ""
+	if result.Err != nil {
+		job.Error = result.Err.Error()
+	}
+	job.UpdatedAt = now
+	job.FinishedAt = now
+	_, err := s.Exec("UPDATE bc_register_job SET status = ?, active_address = NULL, tx_hash = ?, receipt_status = ?, result = ?, error = ?, locked_by = '', updated_at = ?, finished_at = ? WHERE id = ?",
+		job.Status, job.TxHash, job.ReceiptStatus, job.Result, job.Error, job.UpdatedAt, job.FinishedAt, job.Id)
+	if err != nil {
+		logger.Error("更新注册任务失败", logKeyAddress, job.Address, "job_id", job.Id, "error", err)
+		return
+	}
+	s.insertRoleAudit(&RoleAudit{
+		Action:        roleActionGrant,
+		Address:       job.Address,
+		Actor:         job.Caller,
+		RemoteIP:      job.RemoteIP,
+		RequestId:     job.RequestId,
+		TxHash:        job.TxHash,
+		ReceiptStatus: job.ReceiptStatus,
+		Outcome:       job.Result,
+		Error:         job.Error,
+	})
+	if job.CallbackUrl != "" {
+		go s.notifyRegisterWebhook(*job)
+	}
+}
+
+// notifyRegisterWebhook 将任务最终结果签名后 POST 到 callback_url，失败最多重试 3 次
+func (s *SQL) notifyRegisterWebhook(job RegisterJob) {
+	l := logger.With(logKeyAddress, job.Address, "job_id", job.Id, "callback_url", job.CallbackUrl)
+	secret := cfg.Register.WebhookSecret
+	if secret == "" {
+		l.Warn("未配置 REGISTER_WEBHOOK_SECRET，跳过回调通知")
+		return
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		l.Error("转换为JSON失败", "error", err)
+		return
+	}
+	statusCode := 0
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * 5 * time.Second)
+		}
+		request, err := http.NewRequest(http.MethodPost, job.CallbackUrl, bytes.NewReader(body))
+		if err != nil {
+			l.Error("创建回调请求失败", "error", err)
+			return
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(webhookTimestampHeader, timestamp)
+		request.Header.Set(webhookSignatureHeader, signWebhook(secret, timestamp, body))
+		response, err := webhookClient.Do(request)
+		if err != nil {
+			l.Warn("回调通知失败", "error", err)
+			continue
+		}
+		response.Body.Close()
+		statusCode = response.StatusCode
+		if statusCode >= 200 && statusCode < 300 {
+			break
+		}
+		l.Warn("回调通知失败", "status_code", statusCode)
+	}
+	_, err = s.Exec("UPDATE bc_register_job SET callback_status = ? WHERE id = ?", statusCode, job.Id)
+	if err != nil {
+		l.Error("更新回调状态失败", "error", err)
+	}
+}
+
+// signWebhook 回调请求的签名，接收方按同样方式计算并比较，同时检查时间戳防止重放
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkCallbackUrl 校验回调地址：未配置签名密钥时不接受回调；只允许 http、https，
+// 且主机解析出的地址都必须是公网地址，返回的错误信息直接作为响应的 msg
+func checkCallbackUrl(callbackUrl string) error {
+	if cfg.Register.WebhookSecret == "" {
+		return errors.New("callback_url is not enabled")
+	}
+	u, err := url.Parse(callbackUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("Invalid callback_url")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return errors.New("callback_url host cannot be resolved")
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return errors.New("callback_url must resolve to a public address")
+		}
+	}
+	return nil
+}
+
+// dialPublicOnly 拒绝连接非公网地址
+func dialPublicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("callback address %s is not public", host)
+	}
+	return nil
+}
+
+// isPublicIP 排除回环、私有、链路本地（含 169.254.169.254 等元数据地址）、组播和未指定地址
+func isPublicIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// getRegisterJob 查询注册任务状态
+func (srv *Server) getRegisterJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Invalid job id")
+		return
+	}
+
+	s := srv.sql
+	job, err := s.getRegisterJob(id)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	if job == nil {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "Job not found")
+		return
+	}
+
+	response := ResponseList{
+		Data: job,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
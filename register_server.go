@@ -2,25 +2,21 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/go-sql-driver/mysql"
-	"github.com/joho/godotenv"
 )
 
 var (
@@ -35,13 +31,21 @@ var (
 	dbPassword               string
 	dbPort                   string
 	dbHost                   string
+	dbDriver                 string
+	dbPath                   string
+	dbSSLMode                string
 	rpcUrl                   string
-	accountTaskStatus        string
-	pullTaskStatus           string
+	adminToken               string
+	roleQueryMethod          string
+	roleRevokeMethod         string
+	rpcHistoricalCall        bool
+	accountTaskStatus        bool
+	pullTaskStatus           bool
 )
 
 type Input struct {
-	Address string `json:"address"`
+	Address     string `json:"address"`
+	CallbackUrl string `json:"callback_url"`
 }
 
 type Response struct {
@@ -68,6 +72,8 @@ type AccountQueryList struct {
 	Page     int               `json:"page"`
 	PageSize int               `json:"pagesize"`
 	Total    int               `json:"total"`
+	Sort     string            `json:"sort,omitempty"`
+	Window   string            `json:"window,omitempty"`
 }
 
 type JSONRPCRequest struct {
@@ -106,16 +112,25 @@ type Transaction struct {
 }
 
 type TransactionReceipt struct {
-	Hash    string `json:"transactionHash"`
-	From    string `json:"from"`
-	To      string `json:"to"`
-	Status  int    `json:"status"`
-	Input   string `json:"input"`
-	Output  string `json:"output"`
-	GasUsed string `json:"gasUsed"`
+	Hash        string     `json:"transactionHash"`
+	BlockNumber int        `json:"blockNumber"`
+	From        string     `json:"from"`
+	To          string     `json:"to"`
+	Status      int        `json:"status"`
+	Input       string     `json:"input"`
+	Output      string     `json:"output"`
+	GasUsed     string     `json:"gasUsed"`
+	Logs        []LogEntry `json:"logEntries"`
+}
+
+type LogEntry struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
 }
 
 type TransactionResponse struct {
+	Id          int64  `json:"-"`
 	BlockNumber string `json:"block_num"`
 	Hash        string `json:"trans_hash"`
 	From        string `json:"from"`
@@ -138,6 +153,7 @@ type TransactionResponse struct {
 }
 
 type TransactionResResponse struct {
+	Id              int64  `json:"-"`
 	BlockNumber     string `json:"block_num"`
 	Hash            string `json:"trans_hash"`
 	From            string `json:"from"`
@@ -158,178 +174,93 @@ type AccountResponse struct {
 	Balance   int    `json:"balance"`
 	Cred      int    `json:"cred"`
 	ShareNnum int    `json:"share_num"`
+	Rank      int    `json:"rank,omitempty"`
+	Score     int    `json:"score,omitempty"`
 }
 
 type SQL struct {
-	db *sql.DB
+	db    *sql.DB
+	store Storage
 }
 
 func main() {
-	initEnvConfig()
-	initDB()
-	initBlockTask()
-	initListen()
+	os.Exit(runCommand(os.Args[1:]))
 }
 
-func initListen() {
-	http.HandleFunc("/register", handleRequest)
-	http.HandleFunc("/contract-address", getContractAddress)
-	http.HandleFunc("/getTransByAddress", getTransByAddress)
-	http.HandleFunc("/getResByAddress", getResByAddress)
-	http.HandleFunc("/accountRanking", accountRanking)
-	fmt.Printf("服务端口: %s\n", port)
-	fmt.Printf("当前Cred合约地址: %s\n", contractAddress)
-	err := http.ListenAndServe(":"+port, nil)
+func initListen(srv *Server) {
+	logger.Info("服务端口", "port", port)
+	logger.Info("当前Cred合约地址", "contract_address", contractAddress)
+	err := http.ListenAndServe(":"+port, srv.routes())
 	if err != nil {
-		log.Fatal("Failed to start server:", err)
+		logFatal(logger, "Failed to start server", "error", err)
 	}
 
 }
 
-func initBlockTask() {
-	fmt.Printf("加载区块同步任务...\n")
+func initBlockTask(s *SQL) {
+	logger.Info("加载区块同步任务...")
 	executeRequestTaskStatus = false
 	// 启动异步任务
-	if pullTaskStatus == "on" {
-		go executeRequestTask()
+	if pullTaskStatus {
+		go executeRequestTask(s)
 	}
-	if accountTaskStatus == "on" {
-		go executeAccountTask()
+	if accountTaskStatus {
+		go executeAccountTask(s)
 	}
+	initRegisterJobTask(s)
 
 }
 
-func initEnvConfig() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Failed to load .env file:", err)
-	}
-	port = os.Getenv("PORT")
-	dbase = os.Getenv("DB_DATABASE")
-	dbUsername = os.Getenv("DB_USERNAME")
-	dbPassword = os.Getenv("DB_PASSWORD")
-	dbPort = os.Getenv("DB_PORT")
-	dbHost = os.Getenv("DB_HOST")
-	dbTablePrefix = os.Getenv("DB_PREFIX")
-	rpcUrl = os.Getenv("RPC_URL")
-	abiStr = os.Getenv("CONTRACT_ABI")
-	contractAddress = os.Getenv("CONTRACT_ADDRESS")
-	contractMethodId = os.Getenv("CONTRACT_METHOD_SHARADATE")
-	accountTaskStatus = os.Getenv("ACCOUNT_TASK_STATUS")
-	pullTaskStatus = os.Getenv("PULL_TASK_STATUS")
-	if port == "" {
-		port = "5924" // 当未在 .env 文件中指定 PORT 时，默认使用 5000
-	}
-}
-
-func initDB() {
+// initDB 创建全局共享的连接池并执行建表，服务和后台任务都使用这一个连接池
+func initDB() *SQL {
 	sql := NewSQL()
 	sql.migrate()
-	sql.db.Close()
+	return sql
 }
 
+// 启动时检查数据库连接的超时时间
+const dbPingTimeout = 10 * time.Second
+
+// NewSQL 按 DB_DRIVER 打开连接池，连接数和连接生命周期由 DB_MAX_OPEN_CONNS、DB_MAX_IDLE_CONNS、DB_CONN_MAX_LIFETIME_MINUTES 配置。
+// 打开后立即检查数据库是否可以连接，连接失败时退出
 func NewSQL() *SQL {
 	// 数据库连接
-	s := &SQL{}
-	dsn := dbUsername + ":" + dbPassword + "@tcp" + "(" + dbHost + ":" + dbPort + ")/" + dbase
-	db, err := sql.Open("mysql", dsn)
+	store, err := newStorage(dbDriver)
 	if err != nil {
 		panic(err.Error())
 	}
-	s.db = db
-	return s
-}
-
-func (s *SQL) migrate() {
-	// 创建数据库
-	_, err := s.db.Exec("CREATE DATABASE IF NOT EXISTS " + dbase)
+	db, err := store.Open()
 	if err != nil {
 		panic(err.Error())
 	}
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.DB.ConnMaxLifetimeMinutes) * time.Minute)
 
-	// 切换到新创建的数据库
-	_, err = s.db.Exec("USE " + dbase)
-	if err != nil {
-		panic(err.Error())
+	ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		logFatal(logger, "无法连接数据库，请检查 DB_DRIVER、DB_HOST、DB_PORT、DB_DATABASE、DB_USERNAME、DB_PASSWORD、DB_PATH",
+			"driver", store.Name(), "host", dbHost, "port", dbPort, "database", dbase, "path", dbPath, "error", err)
 	}
+	return &SQL{db: db, store: store}
+}
 
-	// 创建数据表
-	tableName_1 := dbTablePrefix + "block_number"
-	tableSql_1 := fmt.Sprintf(`
-	CREATE TABLE IF NOT EXISTS %s (
-		id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		block_num int(11) NOT NULL DEFAULT 0,
-		block_hash varchar(100) NOT NULL DEFAULT '',
-		block_transactions longtext DEFAULT '',
-		response_code int(8) NOT NULL DEFAULT 0,
-		status tinyint(4) NOT NULL DEFAULT 0,
-		PRIMARY KEY (id),
-		UNIQUE KEY block_num (block_num) USING BTREE
-	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`, tableName_1)
-
-	_, err = s.db.Exec(tableSql_1)
-	if err != nil {
-		panic(err.Error())
-	}
-	fmt.Printf("加载表[%v]成功\n", tableName_1)
-
-	tableName_2 := dbTablePrefix + "block_transactions"
-	tableSql_2 := fmt.Sprintf(`
-	CREATE TABLE IF NOT EXISTS %s (
-		id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		block_num int(11) NOT NULL DEFAULT 0,
-		trans_hash varchar(100) NOT NULL DEFAULT '',
-		`+"`from`"+` varchar(100) NOT NULL,
-		`+"`to`"+` varchar(100) NOT NULL,
-		input longtext DEFAULT '',
-		decode_input longtext DEFAULT '',
-		is_contract tinyint(4) NOT NULL DEFAULT 0,
-		method_id varchar(20) DEFAULT NULL,
-		output longtext DEFAULT '',
-		decode_output longtext DEFAULT '',
-		status int(10) NOT NULL DEFAULT -1,
-		gas_used varchar(100) NOT NULL DEFAULT '0',
-		import_time bigint(20) unsigned NOT NULL DEFAULT 0,
-		PRIMARY KEY (id),
-		UNIQUE KEY trans_hash (trans_hash) USING BTREE,
-		KEY `+"`from`"+` (`+"`from`"+`) USING BTREE,
-		KEY `+"`to`"+` (`+"`to`"+`) USING BTREE
-	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`, tableName_2)
-
-	_, err = s.db.Exec(tableSql_2)
-	if err != nil {
-		panic(err.Error())
-	}
-	fmt.Printf("加载表[%v]成功\n", tableName_2)
-
-	tableName_3 := dbTablePrefix + "block_account"
-	tableSql_3 := fmt.Sprintf(`
-	CREATE TABLE IF NOT EXISTS %s (
-		id int(11) unsigned NOT NULL AUTO_INCREMENT,
-		address varchar(100) NOT NULL DEFAULT '',
-		balance int(10) NOT NULL DEFAULT 0,
-		cred int(10) NOT NULL DEFAULT 0,
-		share_num int(10) NOT NULL DEFAULT 0,
-		PRIMARY KEY (id),
-		UNIQUE KEY address (address) USING BTREE
-	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`, tableName_3)
-
-	_, err = s.db.Exec(tableSql_3)
+func (s *SQL) migrate() {
+	err := s.store.Migrate(s.db)
 	if err != nil {
 		panic(err.Error())
 	}
-	fmt.Printf("加载表[%v]成功\n", tableName_3)
-	fmt.Println("数据库加载成功！")
+	logger.Info("数据库加载成功！", "driver", s.store.Name())
 
 }
 
-func executeRequestTask() {
+func executeRequestTask(sql *SQL) {
 	ticker := time.NewTicker(1 * time.Second)
 	executeRequestTaskStatus = true
 	if executeRequestTaskStatus {
-		fmt.Printf("区块同步任务加载完成!\n")
+		logger.Info("区块同步任务加载完成!")
 	}
-	sql := NewSQL()
 
 	for {
 		<-ticker.C // 等待计时器触发
@@ -339,31 +270,38 @@ func executeRequestTask() {
 			continue
 		}
 		difNum := currentBlockNumber - maxBlockNum
-		fmt.Printf("检测到高度差异[%v]，最新区块高度[%v]，本地区块高度[%v]，执行区块同步任务。。\n", difNum, currentBlockNumber, maxBlockNum)
+		logger.Info("检测到高度差异，执行区块同步任务", "diff", difNum, "chain_height", currentBlockNumber, "local_height", maxBlockNum)
 		if difNum > 0 {
 			maxBlockNum++
-			fmt.Printf("读取区块[%v]\n", maxBlockNum)
+			blockLogger(maxBlockNum).Debug("读取区块")
 			sql.synBlockTask(maxBlockNum)
 		}
-		defer sql.db.Close()
 	}
 
 }
 
-func executeAccountTask() {
-	ticker := time.NewTicker(60 * time.Second) // 60s
-	sql := NewSQL()
+// executeAccountTask 定时刷新被区块同步标记为 dirty 的帐户，
+// 并按 ACCOUNT_FULL_SYNC_MINUTES（默认 60 分钟）做一次全量校准
+func executeAccountTask(sql *SQL) {
+	ticker := time.NewTicker(5 * time.Second)
+	fullSyncInterval := time.Duration(cfg.Tasks.AccountFullSyncMinutes) * time.Minute
+	lastFullSync := time.Now()
 	for {
 		<-ticker.C // 等待计时器触发
-		sql.synAccountTask()
-		defer sql.db.Close()
+		if time.Since(lastFullSync) >= fullSyncInterval {
+			logger.Info("全量校准帐户")
+			sql.synAccountTask()
+			lastFullSync = time.Now()
+			continue
+		}
+		sql.synDirtyAccountTask()
 	}
 }
 
 func (s *SQL) synAccountTask() {
 	// 查询所有 address
 	query := `SELECT address FROM bc_block_account`
-	rows, err := s.db.Query(query)
+	rows, err := s.Query(query)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -383,191 +321,74 @@ func (s *SQL) synAccountTask() {
 
 	// 更新 account
 	for _, account := range accounts {
-		s.synUpdateAccount(account.Address)
-	}
-}
-
-func (s *SQL) synUpdateAccount(address string) {
-	balance := synAccountBalcance(address)
-	cred := synAccountCred(address)
-	shareNum := s.synAccountShareNum(address)
-	// fmt.Printf("%v||%v||%v\n", balance, cred, shareNum)
-	_, err := s.db.Exec("UPDATE bc_block_account SET balance = ?, cred = ?, share_num = ? WHERE address = ?",
-		balance, cred, shareNum, address)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func synAccountBalcance(address string) int64 {
-	// 加载合约
-	abi, err := abi.JSON(strings.NewReader(abiStr))
-	if err != nil {
-		log.Fatal(err)
-	}
-	toAddress := common.HexToAddress(address)
-	// encodeTo, err := hex.DecodeString(to[2:])
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// ***************  查积分  *********************
-
-	// 将参数按照 ABI 格式编码为字节数组
-	data, err := abi.Pack("balance", toAddress)
-	if err != nil {
-		panic(err)
-	}
-	// 将字节数组转换为十六进制字符串
-	hexData := hexutil.Encode(data)
-	// 构建请求体
-	requestData := JSONRPCRequest{
-		Jsonrpc: "2.0",
-		Method:  "call",
-		Params: []interface{}{
-			"group0",
-			"",
-			contractAddress,
-			hexData,
-		},
-		Id: 1,
-	}
-	requestBody, err := json.Marshal(requestData)
-	if err != nil {
-		fmt.Println("Failed to marshal request data:", err)
-		panic(err)
-	}
-
-	// 发送 POST 请求
-	response, err := http.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		fmt.Println("Failed to send request:", err)
-		panic(err)
-	}
-	defer response.Body.Close()
-
-	// 读取响应数据
-	responseBody, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Println("Failed to read response body:", err)
-		panic(err)
-	}
-
-	// 解析响应数据
-	var jsonResponse JSONRPCResponse
-	err = json.Unmarshal(responseBody, &jsonResponse)
-	if err != nil {
-		fmt.Println("Failed to unmarshal response body:", err)
-		panic(err)
-	}
-
-	var balanceReceipt TransactionReceipt
-	err = json.Unmarshal(jsonResponse.Result, &balanceReceipt)
-	if err != nil {
-		fmt.Println("Failed to unmarshal result field:", err)
-		panic(err)
-	}
-	// fmt.Printf("output is: %v\n", )
-
-	balance, err := strconv.ParseInt(balanceReceipt.Output[2:], 16, 64)
-	if err != nil {
-		panic(err.Error())
+		s.clearAccountDirty(account.Address)
+		if err := s.synUpdateAccount(account.Address, 0); err != nil {
+			logger.Error("刷新帐户失败", logKeyAddress, account.Address, "error", err)
+		}
 	}
-
-	// fmt.Println(balance) // 输出十进制结果
-	return balance
 }
 
-func synAccountCred(address string) int64 {
-	// 加载合约
-	abi, err := abi.JSON(strings.NewReader(abiStr))
+// synUpdateAccount 从链上刷新帐户，有变化时记录历史。blockNum 为引起变化的区块，
+// 未知（全量校准、手动刷新）时为 0，历史记录取当前已同步的最新区块。
+// 节点或数据库出错时不修改帐户并返回错误
+func (s *SQL) synUpdateAccount(address string, blockNum int) error {
+	l := logger.With(logKeyAddress, address)
+	balance, err := callContractUint("balance", address, 0)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("query balance: %w", err)
 	}
-	toAddress := common.HexToAddress(address)
-	// encodeTo, err := hex.DecodeString(to[2:])
+	cred, err := callContractUint("cred", address, 0)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("query cred: %w", err)
 	}
-
-	// ***************  查积分  *********************
-
-	// 将参数按照 ABI 格式编码为字节数组
-	data, err := abi.Pack("cred", toAddress)
-	if err != nil {
-		panic(err)
-	}
-	// 将字节数组转换为十六进制字符串
-	hexData := hexutil.Encode(data)
-	// 构建请求体
-	requestData := JSONRPCRequest{
-		Jsonrpc: "2.0",
-		Method:  "call",
-		Params: []interface{}{
-			"group0",
-			"",
-			contractAddress,
-			hexData,
-		},
-		Id: 1,
-	}
-	requestBody, err := json.Marshal(requestData)
-	if err != nil {
-		fmt.Println("Failed to marshal request data:", err)
-		panic(err)
-	}
-
-	// 发送 POST 请求
-	response, err := http.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
+	shareNum, err := s.synAccountShareNum(address)
 	if err != nil {
-		fmt.Println("Failed to send request:", err)
-		panic(err)
+		return fmt.Errorf("query share_num: %w", err)
 	}
-	defer response.Body.Close()
+	l.Debug("更新帐户", "balance", balance, "cred", cred, "share_num", shareNum)
 
-	// 读取响应数据
-	responseBody, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Println("Failed to read response body:", err)
-		panic(err)
+	// 读取旧值，有变化时记录历史
+	old := AccountResponse{}
+	err = s.QueryRow("SELECT balance, cred, share_num FROM bc_block_account WHERE address = ?", address).Scan(&old.Balance, &old.Cred, &old.ShareNnum)
+	if err == sql.ErrNoRows {
+		return nil
 	}
-
-	// 解析响应数据
-	var jsonResponse JSONRPCResponse
-	err = json.Unmarshal(responseBody, &jsonResponse)
 	if err != nil {
-		fmt.Println("Failed to unmarshal response body:", err)
-		panic(err)
+		return fmt.Errorf("query account: %w", err)
 	}
-
-	var credReceipt TransactionReceipt
-	err = json.Unmarshal(jsonResponse.Result, &credReceipt)
-	if err != nil {
-		fmt.Println("Failed to unmarshal result field:", err)
-		panic(err)
+	if int64(old.Balance) == balance && int64(old.Cred) == cred && old.ShareNnum == shareNum {
+		return nil
 	}
 
-	cred, err := strconv.ParseInt(credReceipt.Output[2:], 16, 64)
+	_, err = s.Exec("UPDATE bc_block_account SET balance = ?, cred = ?, share_num = ? WHERE address = ?",
+		balance, cred, shareNum, address)
 	if err != nil {
-		panic(err.Error())
-	}
-
-	// fmt.Println(cred) // 输出十进制结果
-	return cred
+		return fmt.Errorf("update account: %w", err)
+	}
+	leaderboard.update(address, int(balance), int(cred), shareNum)
+	s.insertAccountHistory(AccountHistory{
+		Address:     address,
+		BlockNum:    blockNum,
+		OldBalance:  old.Balance,
+		NewBalance:  int(balance),
+		OldCred:     old.Cred,
+		NewCred:     int(cred),
+		OldShareNum: old.ShareNnum,
+		NewShareNum: shareNum,
+	})
+	return nil
 }
 
-func (s *SQL) synAccountShareNum(address string) int {
+func (s *SQL) synAccountShareNum(address string) (int, error) {
 	// 获取总数
 	countQuery := "SELECT COUNT(*) FROM bc_block_transactions WHERE method_id = ? AND `status` = 0 AND `from` = ?"
 	var total int
-	err := s.db.QueryRow(countQuery, contractMethodId, address).Scan(&total)
-	if err != nil {
-		panic(err)
-	}
-	return total
+	err := s.QueryRow(countQuery, contractMethodId, address).Scan(&total)
+	return total, err
 }
 
 func (s *SQL) checkBlock() (_currentBlockNumber int, _maxBlockNum int) {
+	l := logger
 	// 获取最新区块高度
 	// 构建请求体
 	requestData := JSONRPCRequest{
@@ -581,14 +402,14 @@ func (s *SQL) checkBlock() (_currentBlockNumber int, _maxBlockNum int) {
 	}
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
-		fmt.Println("Failed to marshal request data:", err)
+		l.Error("Failed to marshal request data", "error", err)
 		return
 	}
 
 	// 发送 POST 请求
 	response, err := http.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
-		fmt.Println("Failed to send request:", err)
+		l.Error("Failed to send request", "error", err)
 		return
 	}
 	defer response.Body.Close()
@@ -596,7 +417,7 @@ func (s *SQL) checkBlock() (_currentBlockNumber int, _maxBlockNum int) {
 	// 读取响应数据
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		fmt.Println("Failed to read response body:", err)
+		l.Error("Failed to read response body", "error", err)
 		return
 	}
 
@@ -604,34 +425,35 @@ func (s *SQL) checkBlock() (_currentBlockNumber int, _maxBlockNum int) {
 	var jsonResponse JSONRPCResponse
 	err = json.Unmarshal(responseBody, &jsonResponse)
 	if err != nil {
-		fmt.Println("Failed to unmarshal response body:", err)
+		l.Error("Failed to unmarshal response body", "error", err)
 		return
 	}
 	currentBlockNumberString := string(jsonResponse.Result)
 	num, err := strconv.Atoi(currentBlockNumberString)
 	if err != nil {
-		fmt.Println("转换失败:", err)
+		l.Error("转换失败", "result", currentBlockNumberString, "error", err)
 		return
 	}
 	currentBlockNumber := num
-	// fmt.Println("block number", currentBlockNumber)
 	// 获取数据库最新高度
 	// 查询最大的 block_num 值
 	var maxBlockNum int
-	err = s.db.QueryRow("SELECT COALESCE(MAX(block_num), 0) FROM bc_block_number").Scan(&maxBlockNum)
+	err = s.QueryRow("SELECT COALESCE(MAX(block_num), 0) FROM bc_block_number").Scan(&maxBlockNum)
 	if err != nil {
 		panic(err.Error())
 	}
-	// fmt.Println("最大的 block_num 值为:", maxBlockNum)
 	return currentBlockNumber, maxBlockNum
 }
 
+// addressTasks 区块同步中异步添加帐户地址的任务，一次性命令退出前等待这些任务完成
+var addressTasks sync.WaitGroup
+
 func (s *SQL) synBlockTask(block_num int) {
+	l := blockLogger(block_num)
 	// 加载合约
 	abi, err := abi.JSON(strings.NewReader(abiStr))
 	if err != nil {
-		fmt.Println("加载合约失败")
-		log.Fatal(err)
+		logFatal(l, "加载合约失败", "error", err)
 	}
 
 	// 构建请求体
@@ -649,14 +471,14 @@ func (s *SQL) synBlockTask(block_num int) {
 	}
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
-		fmt.Println("构建请求体失败", err)
+		l.Error("构建请求体失败", "error", err)
 		return
 	}
 
 	// 发送 POST 请求
 	response, err := http.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
-		fmt.Println("Failed to send request:", err)
+		l.Error("Failed to send request", "error", err)
 		return
 	}
 	defer response.Body.Close()
@@ -664,7 +486,7 @@ func (s *SQL) synBlockTask(block_num int) {
 	// 读取响应数据
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		fmt.Println("Failed to read response body:", err)
+		l.Error("Failed to read response body", "error", err)
 		return
 	}
 
@@ -672,27 +494,24 @@ func (s *SQL) synBlockTask(block_num int) {
 	var jsonResponse JSONRPCResponse
 	err = json.Unmarshal(responseBody, &jsonResponse)
 	if err != nil {
-		fmt.Println("Failed to unmarshal response body:", err)
+		l.Error("Failed to unmarshal response body", "error", err)
 		return
 	}
-	// fmt.Println("Response status code:", response.StatusCode)
 	// 解析 result 字段，获取 hash、number 和 transactions
 	var blockInfo BlockInfo
 	err = json.Unmarshal(jsonResponse.Result, &blockInfo)
 	if err != nil {
-		fmt.Println("Failed to unmarshal result field:", err)
+		l.Error("Failed to unmarshal result field", "error", err)
 		return
 	}
 
 	// 输出解析结果
-	fmt.Println("Hash:", blockInfo.Hash)
-	fmt.Println("Number:", blockInfo.Number)
-	fmt.Println("Transactions:")
+	l.Debug("读取区块成功", "block_hash", blockInfo.Hash, "tx_count", len(blockInfo.Transactions))
+	// 区块中涉及的帐户，同步完成后标记为 dirty
+	dirtyAddresses := make([]string, 0)
 	for _, tx := range blockInfo.Transactions {
-		fmt.Printf(" - Hash: %s\n", tx.Hash)
-		fmt.Printf("   From: %s\n", tx.From)
-		fmt.Printf("   To: %s\n", tx.To)
-		// fmt.Printf("   Input: %s\n", tx.Input)
+		txl := l.With(logKeyTxHash, tx.Hash, logKeyAddress, tx.From)
+		txl.Debug("读取交易", "to", tx.To)
 		decode_input := ""
 		is_contract := 0
 		method_id := ""
@@ -702,69 +521,73 @@ func (s *SQL) synBlockTask(block_num int) {
 			method_id = tx.Input[2:10]
 			decodedSig, err := hex.DecodeString(method_id)
 			if err != nil {
-				log.Fatal(err)
+				logFatal(txl, "解析方法id失败", "error", err)
 			}
 			if method_id == contractMethodId {
 				// 加载合约方法
 				method, err := abi.MethodById(decodedSig)
 				if err != nil {
-					log.Fatal(err)
+					logFatal(txl, "加载合约方法失败", "error", err)
 				}
 				decodedData, err := hex.DecodeString(tx.Input[10:])
 				if err != nil {
-					log.Fatal(err)
+					logFatal(txl, "解析input失败", "error", err)
 				}
 				re, err := method.Inputs.Unpack(decodedData)
 				if err != nil {
-					// fmt.Printf("解码失败：%v\n", err)
-					log.Fatal(err)
+					logFatal(txl, "解码失败", "error", err)
 				}
 				jsonData, err := json.Marshal(re)
 				if err != nil {
-					// fmt.Printf("转换为JSON失败：%v\n", err)
-					log.Fatal(err)
+					logFatal(txl, "转换为JSON失败", "error", err)
 				}
 				decode_input = string(jsonData)
+				dirtyAddresses = append(dirtyAddresses, collectAddresses(re)...)
 			}
 
 		}
 
-		_, err = s.db.Exec("INSERT INTO bc_block_transactions (block_num, trans_hash, `from`, `to`, input, decode_input, is_contract, method_id, import_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-			blockInfo.Number, tx.Hash, tx.From, tx.To, tx.Input, decode_input, is_contract, method_id, tx.ImportTime)
+		// 地址统一按小写存储，PostgreSQL 的 varchar 比较区分大小写
+		from, to := strings.ToLower(tx.From), strings.ToLower(tx.To)
+		_, inserted, err := s.InsertIgnore("INSERT INTO bc_block_transactions (block_num, trans_hash, `from`, `to`, input, decode_input, is_contract, method_id, import_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			blockInfo.Number, tx.Hash, from, to, tx.Input, jsonOrNull(decode_input), is_contract, method_id, tx.ImportTime)
 		if err != nil {
-			if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
-				// 错误代码 1062 表示唯一性约束错误
-				fmt.Printf("区块[%v]交易已存在：%v\n", blockInfo.Number, tx.Hash)
-			} else {
-				fmt.Printf("区块[%v]交易存储失败：%v\n", blockInfo.Number, tx.Hash)
-				log.Fatal(err)
-			}
+			logFatal(txl, "区块交易存储失败", "error", err)
 		}
-		s.synTransReceipt(tx.Hash)
-		go s.synAddAddress(tx.From)
+		if !inserted {
+			txl.Warn("区块交易已存在")
+		}
+		dirtyAddresses = append(dirtyAddresses, s.synTransReceipt(tx.Hash)...)
+		dirtyAddresses = append(dirtyAddresses, from, to)
+		addressTasks.Add(1)
+		go func(address string) {
+			defer addressTasks.Done()
+			s.synAddAddress(address)
+		}(from)
 	}
+	s.markAccountsDirty(block_num, dirtyAddresses)
 
 	// 插入区块信息到数据库
 	txJSON, err := json.Marshal(blockInfo.Transactions)
 	if err != nil {
-		log.Fatal(err)
+		logFatal(l, "转换为JSON失败", "error", err)
 	}
-	_, err = s.db.Exec("INSERT INTO bc_block_number (block_num, block_hash, block_transactions, response_code, status) VALUES (?, ?, ?, ?, ?)",
+	_, err = s.Exec("INSERT INTO bc_block_number (block_num, block_hash, block_transactions, response_code, status) VALUES (?, ?, ?, ?, ?)",
 		blockInfo.Number, blockInfo.Hash, string(txJSON), response.StatusCode, 1)
 	if err != nil {
-		fmt.Printf("区块存储失败[%v]\n", blockInfo.Number)
-		log.Fatal(err)
+		logFatal(l, "区块存储失败", "error", err)
 	}
 
-	fmt.Printf("区块存储成功[%v]\n", blockInfo.Number)
+	l.Info("区块存储成功", "block_hash", blockInfo.Hash, "tx_count", len(blockInfo.Transactions))
 }
 
-func (s *SQL) synTransReceipt(tran_hash string) {
+// synTransReceipt 同步交易回执，返回回执事件中涉及的帐户地址
+func (s *SQL) synTransReceipt(tran_hash string) []string {
+	l := logger.With(logKeyTxHash, tran_hash)
 	// 加载合约
 	abi, err := abi.JSON(strings.NewReader(abiStr))
 	if err != nil {
-		fmt.Println("加载合约失败")
-		log.Fatal(err)
+		logFatal(l, "加载合约失败", "error", err)
 	}
 
 	// 构建请求体
@@ -781,37 +604,37 @@ func (s *SQL) synTransReceipt(tran_hash string) {
 	}
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
-		fmt.Println("构建请求体失败", err)
-		return
+		l.Error("构建请求体失败", "error", err)
+		return nil
 	}
 
 	// 发送 POST 请求
 	response, err := http.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
-		fmt.Println("Failed to send request:", err)
-		return
+		l.Error("Failed to send request", "error", err)
+		return nil
 	}
 	defer response.Body.Close()
 
 	// 读取响应数据
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		fmt.Println("Failed to read response body:", err)
-		return
+		l.Error("Failed to read response body", "error", err)
+		return nil
 	}
 
 	// 解析响应数据
 	var jsonResponse JSONRPCResponse
 	err = json.Unmarshal(responseBody, &jsonResponse)
 	if err != nil {
-		fmt.Println("Failed to unmarshal response body:", err)
-		return
+		l.Error("Failed to unmarshal response body", "error", err)
+		return nil
 	}
 	var transactionReceipt TransactionReceipt
 	err = json.Unmarshal(jsonResponse.Result, &transactionReceipt)
 	if err != nil {
-		fmt.Println("Failed to unmarshal result field:", err)
-		return
+		l.Error("Failed to unmarshal result field", "error", err)
+		return nil
 	}
 	// 解码output
 	decode_output := ""
@@ -821,27 +644,26 @@ func (s *SQL) synTransReceipt(tran_hash string) {
 		method_id = transactionReceipt.Input[2:10]
 		decodedSig, err := hex.DecodeString(method_id)
 		if err != nil {
-			log.Fatal(err)
+			logFatal(l, "解析方法id失败", "error", err)
 		}
 		if method_id == contractMethodId {
 			// 加载合约方法
 			method, err := abi.MethodById(decodedSig)
 			if err != nil {
-				log.Fatal(err)
+				logFatal(l, "加载合约方法失败", "error", err)
 			}
 			decodedOutputData, err := hex.DecodeString(transactionReceipt.Output[2:])
 			if err != nil {
-				log.Fatal(err)
+				logFatal(l, "解析output失败", "error", err)
 			}
 			re, err := method.Outputs.Unpack(decodedOutputData)
 			if err != nil {
-				fmt.Printf("解码失败：%v\n", err)
-				return
+				l.Error("解码失败", "error", err)
+				return nil
 			}
 			jsonData, err := json.Marshal(re)
 			if err != nil {
-				// fmt.Printf("转换为JSON失败：%v\n", err)
-				log.Fatal(err)
+				logFatal(l, "转换为JSON失败", "error", err)
 			}
 			decode_output = string(jsonData)
 		}
@@ -849,44 +671,37 @@ func (s *SQL) synTransReceipt(tran_hash string) {
 	}
 
 	// 更新信息
-	rs, err := s.db.Exec("UPDATE bc_block_transactions SET output = ?, decode_output = ?, status = ?, gas_used = ? WHERE trans_hash = ?",
-		transactionReceipt.Output, decode_output, transactionReceipt.Status, transactionReceipt.GasUsed, transactionReceipt.Hash)
+	rs, err := s.Exec("UPDATE bc_block_transactions SET output = ?, decode_output = ?, status = ?, gas_used = ? WHERE trans_hash = ?",
+		transactionReceipt.Output, jsonOrNull(decode_output), transactionReceipt.Status, transactionReceipt.GasUsed, transactionReceipt.Hash)
 	if err != nil {
-		log.Fatal(err)
+		logFatal(l, "更新交易回执失败", "error", err)
 	}
 	// 判断是否更新成功
 	rowsAffected, err := rs.RowsAffected()
 	if err != nil {
-		log.Fatal(err)
+		logFatal(l, "更新交易回执失败", "error", err)
 	}
 	if rowsAffected == 0 {
-		fmt.Printf("未更新交易回执[%v]\n", transactionReceipt.Hash)
+		l.Warn("未更新交易回执")
 	} else {
-		fmt.Printf("更新交易回执成功[%v]\n", transactionReceipt.Hash)
+		l.Debug("更新交易回执成功", "status", transactionReceipt.Status)
 	}
+	return eventAddresses(abi, transactionReceipt.Logs)
 }
 
 func (s *SQL) synAddAddress(address string) {
-	// 准备插入语句
-	insertStatement := `INSERT INTO bc_block_account (address) VALUES (?)`
-	stmt, err := s.db.Prepare(insertStatement)
+	address = strings.ToLower(address)
+	// 地址已存在时忽略
+	_, inserted, err := s.InsertIgnore("INSERT INTO bc_block_account (address) VALUES (?)", address)
 	if err != nil {
 		panic(err.Error())
 	}
-	defer stmt.Close()
-
-	// 执行插入语句
-	_, err = stmt.Exec(address)
-	if err != nil {
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
-			// 错误代码 1062 表示唯一性约束错误
-			// fmt.Printf("地址 %s 已存在，不在插入数据\n", address)
-			return
-		}
-		panic(err.Error())
+	if !inserted {
+		return
 	}
 
-	fmt.Printf("添加帐户地址[address]")
+	leaderboard.update(address, 0, 0, 0)
+	logger.Info("添加帐户地址", logKeyAddress, address)
 }
 
 func isValidAddress(address string) bool {
@@ -894,88 +709,44 @@ func isValidAddress(address string) bool {
 	return re.MatchString(address)
 }
 
-func writeToFile(output string) error {
-	file, err := os.OpenFile("register_server.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func handleRequest(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
 		return
 	}
 
 	var input Input
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		log.Println("Failed to parse request body:", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		l.Warn("Failed to parse request body", "error", err)
+		writeError(w, http.StatusBadRequest, errCodeBadRequest, "Bad Request")
 		return
 	}
-
-	response := Response{}
-	if !isValidAddress(input.Address) {
-		response.Message = "Invalid address format"
-		response.Code = 0
-		jsonResponse, err := json.Marshal(response)
-		if err != nil {
-			log.Println("Failed to marshal JSON response:", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(jsonResponse)
-		return
-	}
-
-	cmdString := fmt.Sprintf("echo call Cred %s register %s | bash ./console/start.sh", contractAddress, input.Address)
-	cmd := exec.Command("bash", "-c", cmdString)
-	output, err := cmd.CombinedOutput() // 获取命令的标准输出和标准错误输出
+	job, err := srv.submitRegister(l, input, RegisterCaller{
+		Caller:    callerIdentity(r),
+		RemoteIP:  r.RemoteAddr,
+		RequestId: w.Header().Get("X-Request-Id"),
+	})
 	if err != nil {
-		log.Println("Failed to execute command:", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		writeServiceError(w, r, err)
 		return
 	}
 
-	if strings.Contains(string(output), "transaction status: 0") {
-		response.Message = "Authorization successful"
-		response.Code = 1
-	} else if strings.Contains(string(output), "account already has role") {
-		response.Message = "Account already authorized"
-		response.Code = 1
-	} else {
-		response.Message = "Authorization failed"
-		response.Code = 0
-	}
-
-	jsonResponse, err := json.Marshal(response)
+	jsonResponse, err := json.Marshal(ResponseList{
+		Data: job,
+		Msg:  "accepted",
+		Code: 1,
+	})
 	if err != nil {
-		log.Println("Failed to marshal JSON response:", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		l.Error("Failed to marshal JSON response", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/register/job?id=%d", job.Id))
+	w.WriteHeader(http.StatusAccepted)
 	w.Write(jsonResponse)
-
-	// 将命令执行后的结果记录到文件中
-	err = writeToFile(string(cmdString))
-	if err != nil {
-		log.Println("Failed to write to file:", err)
-	}
-	err = writeToFile(string(output))
-	if err != nil {
-		log.Println("Failed to write to file:", err)
-	}
 }
 
 func getContractAddress(w http.ResponseWriter, r *http.Request) {
@@ -985,285 +756,11 @@ func getContractAddress(w http.ResponseWriter, r *http.Request) {
 	response.Code = 1
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
-		log.Println("Failed to marshal JSON response:", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		loggerFromContext(r.Context()).Error("Failed to marshal JSON response", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(jsonResponse)
 	return
 }
-
-func getTransByAddress(w http.ResponseWriter, r *http.Request) {
-	// 获取请求参数
-	queryValues := r.URL.Query()
-	pageStr := queryValues.Get("page")
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
-	}
-	pageSizeStr := queryValues.Get("pagesize")
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize <= 0 || pageSize > 100 {
-		pageSize = 10
-	}
-	// 连接数据库
-	dsn := dbUsername + ":" + dbPassword + "@tcp" + "(" + dbHost + ":" + dbPort + ")/" + dbase
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error connecting to database")
-		return
-	}
-	defer db.Close()
-
-	// 计算偏移量和限制条数
-	offset := (page - 1) * pageSize
-	limit := pageSize
-
-	// 执行查询
-	query := "SELECT block_num, trans_hash, `from`, `to`, `status`, import_time, input, output, heart_rate, breath_rate, sleep_state, person_id, contact_name, contact_identity FROM bc_block_transactions WHERE `from` = ? ORDER BY id DESC LIMIT ?, ?"
-	rows, err := db.Query(query, queryValues.Get("address"), offset, limit)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying database")
-		fmt.Println("Error", err)
-		return
-	}
-	defer rows.Close()
-
-	// 将查询结果映射为 Transaction 数据结构的列表
-	transactions := make([]TransactionResponse, 0)
-	for rows.Next() {
-		transaction := TransactionResponse{}
-		err := rows.Scan(&transaction.BlockNumber, &transaction.Hash, &transaction.From, &transaction.To, &transaction.Status, &transaction.ImportTime, &transaction.Input, &transaction.Output, &transaction.HeartRate, &transaction.BreathRate, &transaction.SleepState, &transaction.PersonId, &transaction.ContactName, &transaction.ContactIdentity)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(w, "Error scanning row data")
-			fmt.Println("Error", err)
-			return
-		}
-		transactions = append(transactions, transaction)
-	}
-	// 获取总数
-	countQuery := "SELECT COUNT(*) FROM bc_block_transactions WHERE `from` = ?"
-	var total int
-	err = db.QueryRow(countQuery, queryValues.Get("address")).Scan(&total)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying total count")
-		fmt.Println("Error", err)
-		return
-	}
-
-	// 构建 Response 结构体
-	response := ResponseList{
-		Data: QueryList{
-			List:     transactions,
-			Page:     page,
-			PageSize: pageSize,
-			Total:    total,
-		},
-		Msg:  "success",
-		Code: 1,
-	}
-
-	// 序列化 Response 结构为 JSON 字符串
-	responseJsonData, err := json.Marshal(response)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error serializing JSON data")
-		return
-	}
-
-	// 设置响应头为 JSON 格式
-	w.Header().Set("Content-Type", "application/json")
-
-	// 发送响应
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseJsonData)
-
-}
-
-func getResByAddress(w http.ResponseWriter, r *http.Request) {
-	// 获取请求参数
-	queryValues := r.URL.Query()
-	pageStr := queryValues.Get("page")
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
-	}
-	pageSizeStr := queryValues.Get("pagesize")
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize <= 0 || pageSize > 100 {
-		pageSize = 10
-	}
-	// 连接数据库
-	dsn := dbUsername + ":" + dbPassword + "@tcp" + "(" + dbHost + ":" + dbPort + ")/" + dbase
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error connecting to database")
-		return
-	}
-	defer db.Close()
-
-	// 计算偏移量和限制条数
-	offset := (page - 1) * pageSize
-	limit := pageSize
-
-	// 执行查询
-
-	query := "SELECT block_num, trans_hash, `from`, `to`, `status`, import_time, input, output, sleep_breathing, heart_change, person_id, contact_name, contact_identity FROM bc_block_transactions WHERE `from` = ? ORDER BY id DESC LIMIT ?, ?"
-	rows, err := db.Query(query, queryValues.Get("address"), offset, limit)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying database")
-		fmt.Println("Error", err)
-		return
-	}
-	defer rows.Close()
-
-	// 将查询结果映射为 Transaction 数据结构的列表
-	transactions := make([]TransactionResResponse, 0)
-	for rows.Next() {
-		transaction := TransactionResResponse{}
-		err := rows.Scan(&transaction.BlockNumber, &transaction.Hash, &transaction.From, &transaction.To, &transaction.Status, &transaction.ImportTime, &transaction.Input, &transaction.Output, &transaction.SleepBreathing, &transaction.HeartChange, &transaction.PersonId, &transaction.ContactName, &transaction.ContactIdentity)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(w, "Error scanning row data")
-			fmt.Println("Error", err)
-			return
-		}
-		transactions = append(transactions, transaction)
-	}
-	// 获取总数
-	countQuery := "SELECT COUNT(*) FROM bc_block_transactions WHERE `from` = ?"
-	var total int
-	err = db.QueryRow(countQuery, queryValues.Get("address")).Scan(&total)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying total count")
-		fmt.Println("Error", err)
-		return
-	}
-
-	// 构建 Response 结构体
-	response := ResponseList{
-		Data: QueryList{
-			List:     transactions,
-			Page:     page,
-			PageSize: pageSize,
-			Total:    total,
-		},
-		Msg:  "success",
-		Code: 1,
-	}
-
-	// 序列化 Response 结构为 JSON 字符串
-	responseJsonData, err := json.Marshal(response)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error serializing JSON data")
-		return
-	}
-
-	// 设置响应头为 JSON 格式
-	w.Header().Set("Content-Type", "application/json")
-
-	// 发送响应
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseJsonData)
-
-}
-
-func accountRanking(w http.ResponseWriter, r *http.Request) {
-	// 获取请求参数
-	queryValues := r.URL.Query()
-	pageStr := queryValues.Get("page")
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page <= 0 {
-		page = 1
-	}
-	pageSizeStr := queryValues.Get("pagesize")
-	pageSize, err := strconv.Atoi(pageSizeStr)
-	if err != nil || pageSize <= 0 || pageSize > 100 {
-		pageSize = 10
-	}
-	// 连接数据库
-	dsn := dbUsername + ":" + dbPassword + "@tcp" + "(" + dbHost + ":" + dbPort + ")/" + dbase
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error connecting to database")
-		return
-	}
-	defer db.Close()
-
-	// 计算偏移量和限制条数
-	offset := (page - 1) * pageSize
-	limit := pageSize
-
-	// 执行查询
-	query := "SELECT address, balance, cred, share_num FROM bc_block_account ORDER BY balance DESC LIMIT ?, ?"
-	rows, err := db.Query(query, offset, limit)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying database")
-		fmt.Println("Error", err)
-		return
-	}
-	defer rows.Close()
-
-	// 将查询结果映射为 Transaction 数据结构的列表
-	accounts := make([]AccountResponse, 0)
-	for rows.Next() {
-		account := AccountResponse{}
-		err := rows.Scan(&account.Address, &account.Balance, &account.Cred, &account.ShareNnum)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(w, "Error scanning row data")
-			fmt.Println("Error", err)
-			return
-		}
-		accounts = append(accounts, account)
-	}
-	// 获取总数
-	countQuery := "SELECT COUNT(*) FROM bc_block_account"
-	var total int
-	err = db.QueryRow(countQuery).Scan(&total)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error querying total count")
-		fmt.Println("Error", err)
-		return
-	}
-
-	// 构建 Response 结构体
-	response := ResponseList{
-		Data: AccountQueryList{
-			List:     accounts,
-			Page:     page,
-			PageSize: pageSize,
-			Total:    total,
-		},
-		Msg:  "success",
-		Code: 1,
-	}
-
-	// 序列化 Response 结构为 JSON 字符串
-	responseJsonData, err := json.Marshal(response)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, "Error serializing JSON data")
-		return
-	}
-
-	// 设置响应头为 JSON 格式
-	w.Header().Set("Content-Type", "application/json")
-
-	// 发送响应
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseJsonData)
-
-}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const testContractAbi = `[
+	{"type":"function","name":"balance","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"cred","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// contractCalls 按方法名返回合约 balance、cred 的调用结果，values 中没有的方法返回失败的回执
+func contractCalls(t *testing.T, values map[string]int64) func(method string, params []interface{}) string {
+	t.Helper()
+	saved := abiStr
+	abiStr = testContractAbi
+	t.Cleanup(func() { abiStr = saved })
+	contractAbi, err := abi.JSON(strings.NewReader(testContractAbi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(method string, params []interface{}) string {
+		if method != "call" || len(params) < 4 {
+			return "null"
+		}
+		data, _ := params[3].(string)
+		for name, m := range contractAbi.Methods {
+			if !strings.HasPrefix(data, hexutil.Encode(m.ID)) {
+				continue
+			}
+			if v, ok := values[name]; ok {
+				return fmt.Sprintf(`{"status":0,"output":"0x%064x"}`, v)
+			}
+		}
+		return `{"status":16,"output":"0x"}`
+	}
+}
+
+func TestSynUpdateAccount(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	mustExec(t, s, "INSERT INTO bc_block_account (address, balance, cred, share_num) VALUES (?, 1, 1, 0)", testAddressA)
+	fakeNode(t, contractCalls(t, map[string]int64{"balance": 30, "cred": 4}))
+
+	if err := s.synUpdateAccount(testAddressA, 3); err != nil {
+		t.Fatal(err)
+	}
+	account, err := newSQLAccountRepo(s).Get(testAddressA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 30 || account.Cred != 4 || account.ShareNnum != 2 {
+		t.Errorf("account = %+v", account)
+	}
+	history, err := newSQLAccountRepo(s).History(testAddressA, 0, 1<<62, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].BlockNum != 3 || history[0].OldBalance != 1 || history[0].NewBalance != 30 {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestSynUpdateAccountNodeError(t *testing.T) {
+	s := newTestSQL(t)
+	mustExec(t, s, "INSERT INTO bc_block_account (address, balance, cred, share_num) VALUES (?, 1, 1, 0)", testAddressA)
+	// cred 调用失败时不修改帐户
+	fakeNode(t, contractCalls(t, map[string]int64{"balance": 30}))
+
+	if err := s.synUpdateAccount(testAddressA, 0); err == nil {
+		t.Fatal("synUpdateAccount should fail when the node call fails")
+	}
+	account, err := newSQLAccountRepo(s).Get(testAddressA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 1 || account.Cred != 1 {
+		t.Errorf("account changed on node error: %+v", account)
+	}
+}
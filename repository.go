@@ -0,0 +1,844 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// TransactionRepo bc_block_transactions 的读操作
+type TransactionRepo interface {
+	// List 按条件分页查询交易（含心率、呼吸等体征字段）
+	List(f TransactionFilter) ([]TransactionResponse, error)
+	// ListRes 按条件分页查询交易（含睡眠呼吸、心率变化字段）
+	ListRes(f TransactionFilter) ([]TransactionResResponse, error)
+	// Count 统计满足条件的交易数，忽略游标和分页
+	Count(f TransactionFilter) (int, error)
+	// ActivityOf 统计地址作为发送方的交易数、成功数，以及作为发送方或接收方出现的首末区块
+	ActivityOf(address string) (AccountActivity, error)
+	// LatestVitals 最近一次成功 shareData 的体征数据，不存在时返回 sql.ErrNoRows
+	LatestVitals(address string) (*VitalsSummary, error)
+	// LatestPerson 最近一次关联的人员信息，不存在时返回 sql.ErrNoRows
+	LatestPerson(address string) (*LinkedPerson, error)
+	// ShareCountAt 截至 blockNum 成功的 shareData 交易数
+	ShareCountAt(address string, blockNum int) (int, error)
+	// Get 按交易哈希查询交易详情（含所在区块哈希），不存在时返回 sql.ErrNoRows
+	Get(hash string) (*TransactionDetail, error)
+	// ListByBlock 区块内的交易，按入库顺序返回
+	ListByBlock(blockNum int) ([]BlockTransaction, error)
+	// Totals 全部交易的数量、成功/失败数、平均 gas 以及 import_time 范围
+	Totals() (TransactionTotals, error)
+	// Daily 从 since（毫秒，UTC 零点）起按天统计交易，只返回有交易的日期
+	Daily(since int64) ([]DailyStats, error)
+	// Export 按条件逐行读取交易（忽略游标和分页），fn 返回错误时停止读取
+	Export(f TransactionFilter, fn func(*TransactionExport) error) error
+	// Query 按条件分页查询交易的全部字段
+	Query(f TransactionFilter) ([]IndexedTransaction, error)
+	// GetMany 按交易哈希批量查询，不存在的哈希不在结果中
+	GetMany(hashes []string) (map[string]IndexedTransaction, error)
+	// ListByBlocks 批量查询区块内的交易，按入库顺序返回
+	ListByBlocks(numbers []int) (map[int][]IndexedTransaction, error)
+	// ListBySenders 批量查询每个发送方最新的 limit 条交易，vitals 为 true 时只查成功的 shareData 交易
+	ListBySenders(addresses []string, vitals bool, limit int) (map[string][]IndexedTransaction, error)
+	// ListByPersons 批量查询每个 person_id 最新的 limit 条交易，vitals 含义同 ListBySenders
+	ListByPersons(ids []int, vitals bool, limit int) (map[int][]IndexedTransaction, error)
+	// PersonsOf 批量查询地址最近一次关联的人员
+	PersonsOf(addresses []string) (map[string]LinkedPerson, error)
+	// Persons 批量查询人员最近一次上报的联系人信息
+	Persons(ids []int) (map[int]LinkedPerson, error)
+	// SendersOf 批量查询人员关联过的发送方地址
+	SendersOf(ids []int) (map[int][]string, error)
+}
+
+// AccountRepo bc_block_account 及帐户相关表的读操作
+type AccountRepo interface {
+	// Get 查询帐户，不存在时返回 sql.ErrNoRows
+	Get(address string) (*AccountResponse, error)
+	All() ([]AccountResponse, error)
+	Count() (int, error)
+	// Ranking 按排行榜条件分页查询，rank 从 offset+1 开始
+	Ranking(opts RankingOptions, offset int, limit int) ([]AccountResponse, error)
+	// Rank 查询地址在排行榜中的名次，不存在时返回 sql.ErrNoRows
+	Rank(opts RankingOptions, address string) (int, error)
+	History(address string, start int64, end int64, limit int) ([]AccountHistory, error)
+	// StateAt 从 bc_account_history 推算帐户在 blockNum 时的状态，没有记录时返回 errStateNotAvailable
+	StateAt(address string, blockNum int) (*AccountStateResponse, error)
+	Registration(address string) (AccountRegistration, error)
+	// GetMany 批量查询帐户，不存在的地址不在结果中
+	GetMany(addresses []string) (map[string]AccountResponse, error)
+}
+
+// BlockRepo bc_block_number 的读操作
+type BlockRepo interface {
+	// MaxBlockNum 已同步的最新区块高度，未同步时返回 0
+	MaxBlockNum() (int, error)
+	// Get 按高度查询已同步的区块，不存在时返回 sql.ErrNoRows
+	Get(number int) (*BlockDetail, error)
+	// GetByHash 按区块哈希查询已同步的区块，不存在时返回 sql.ErrNoRows
+	GetByHash(hash string) (*BlockDetail, error)
+	Count() (int, error)
+	// List 按高度降序查询 before（不含，0 表示不限制）之前的 limit 个区块
+	List(before int, limit int) ([]BlockDetail, error)
+	// ListAfter 按高度升序查询 after（不含）之后的 limit 个区块
+	ListAfter(after int, limit int) ([]BlockDetail, error)
+	// GetMany 按高度批量查询已同步的区块
+	GetMany(numbers []int) (map[int]BlockDetail, error)
+}
+
+// AccountActivity 地址在本地交易表中的活动统计
+type AccountActivity struct {
+	TotalTxCount   int
+	SuccessTxCount int
+	FirstSeenBlock int
+	LastSeenBlock  int
+}
+
+// placeholders 返回 n 个以逗号分隔的 ? 占位符，用于 IN 条件
+func placeholders(n int) string {
+	return "?" + strings.Repeat(", ?", n-1)
+}
+
+// stmtCache 按 SQL 文本缓存预编译语句，每条语句只 Prepare 一次，供所有请求共享
+type stmtCache struct {
+	s     *SQL
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(s *SQL) *stmtCache {
+	return &stmtCache{s: s, stmts: make(map[string]*sql.Stmt)}
+}
+
+func (c *stmtCache) stmt(query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st, ok := c.stmts[query]; ok {
+		return st, nil
+	}
+	st, err := c.s.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = st
+	return st, nil
+}
+
+func (c *stmtCache) query(query string, args ...interface{}) (*sql.Rows, error) {
+	st, err := c.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return st.Query(args...)
+}
+
+func (c *stmtCache) queryRow(query string, args []interface{}, dest ...interface{}) error {
+	st, err := c.stmt(query)
+	if err != nil {
+		return err
+	}
+	return st.QueryRow(args...).Scan(dest...)
+}
+
+// prepare 启动时预编译一组固定语句。体征等字段不在 migrate 中，
+// 预编译失败只记录警告，首次使用时会再次尝试
+func (c *stmtCache) prepare(queries ...string) {
+	for _, query := range queries {
+		if _, err := c.stmt(query); err != nil {
+			logger.Warn("预编译语句失败", "query", query, "error", err)
+		}
+	}
+}
+
+func (c *stmtCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for query, st := range c.stmts {
+		st.Close()
+		delete(c.stmts, query)
+	}
+}
+
+const (
+	txListColumns       = "id, block_num, trans_hash, `from`, `to`, `status`, import_time, input, output, heart_rate, breath_rate, sleep_state, person_id, contact_name, contact_identity"
+	txExportColumns     = "block_num, trans_hash, `from`, `to`, COALESCE(method_id, ''), `status`, gas_used, import_time, decode_input, heart_rate, breath_rate, sleep_state, heart_change, sleep_breathing, person_id, contact_name, contact_identity"
+	txResListColumns    = "id, block_num, trans_hash, `from`, `to`, `status`, import_time, input, output, sleep_breathing, heart_change, person_id, contact_name, contact_identity"
+	txActivityFromQuery = "SELECT COUNT(*), COALESCE(SUM(CASE WHEN `status` = 0 THEN 1 ELSE 0 END), 0), COALESCE(MIN(block_num), 0), COALESCE(MAX(block_num), 0) FROM bc_block_transactions WHERE `from` = ?"
+	txActivityToQuery   = "SELECT COALESCE(MIN(block_num), 0), COALESCE(MAX(block_num), 0) FROM bc_block_transactions WHERE `to` = ?"
+	txLatestVitalsQuery = "SELECT trans_hash, block_num, import_time, heart_rate, breath_rate, sleep_state, heart_change, sleep_breathing FROM bc_block_transactions WHERE `from` = ? AND method_id = ? AND `status` = 0 ORDER BY id DESC LIMIT 1"
+	txLatestPersonQuery = "SELECT person_id, contact_name, contact_identity FROM bc_block_transactions WHERE `from` = ? AND person_id > 0 ORDER BY id DESC LIMIT 1"
+	txShareCountAtQuery = "SELECT COUNT(*) FROM bc_block_transactions WHERE method_id = ? AND `status` = 0 AND `from` = ? AND block_num <= ?"
+	txGetQuery          = "SELECT t.block_num, t.trans_hash, t.`from`, t.`to`, COALESCE(t.input, ''), t.decode_input, COALESCE(t.method_id, ''), COALESCE(t.output, ''), t.decode_output, t.`status`, t.gas_used, t.import_time, COALESCE(b.block_hash, '') FROM bc_block_transactions t LEFT JOIN bc_block_number b ON b.block_num = t.block_num WHERE t.trans_hash = ?"
+	txListByBlockQuery  = "SELECT trans_hash, `from`, `to`, COALESCE(input, ''), COALESCE(method_id, ''), `status`, gas_used, import_time FROM bc_block_transactions WHERE block_num = ? ORDER BY id ASC"
+	txIndexedColumns    = "id, block_num, trans_hash, `from`, `to`, COALESCE(input, ''), decode_input, COALESCE(method_id, ''), COALESCE(output, ''), decode_output, `status`, gas_used, import_time, heart_rate, breath_rate, sleep_state, heart_change, sleep_breathing, person_id, contact_name, contact_identity"
+	// gas_used 为字符串，按 DECIMAL 求平均；回执未同步（status = -1）的交易不计入 gas 和成功/失败
+	txTotalsQuery = "SELECT COUNT(*), COALESCE(SUM(CASE WHEN `status` = 0 THEN 1 ELSE 0 END), 0), COALESCE(SUM(CASE WHEN `status` > 0 THEN 1 ELSE 0 END), 0), " +
+		"COALESCE(AVG(CASE WHEN `status` <> -1 THEN CAST(gas_used AS DECIMAL(20,0)) END), 0), COALESCE(MIN(import_time), 0), COALESCE(MAX(import_time), 0), COUNT(DISTINCT block_num) FROM bc_block_transactions"
+	txDailyQuery = "SELECT import_time - import_time % 86400000 AS day, COUNT(*), COALESCE(SUM(CASE WHEN method_id = ? THEN 1 ELSE 0 END), 0), " +
+		"COALESCE(SUM(CASE WHEN `status` = 0 THEN 1 ELSE 0 END), 0), COALESCE(SUM(CASE WHEN `status` > 0 THEN 1 ELSE 0 END), 0), " +
+		"COALESCE(AVG(CASE WHEN `status` <> -1 THEN CAST(gas_used AS DECIMAL(20,0)) END), 0) FROM bc_block_transactions WHERE import_time >= ? GROUP BY day ORDER BY day"
+)
+
+type sqlTransactionRepo struct {
+	*stmtCache
+}
+
+func newSQLTransactionRepo(s *SQL) *sqlTransactionRepo {
+	r := &sqlTransactionRepo{newStmtCache(s)}
+	r.prepare(txActivityFromQuery, txActivityToQuery, txLatestVitalsQuery, txLatestPersonQuery, txShareCountAtQuery, txGetQuery, txListByBlockQuery, txTotalsQuery, txDailyQuery)
+	return r
+}
+
+// List 查询条件组合有限，语句按组合在首次使用时预编译
+func (r *sqlTransactionRepo) List(f TransactionFilter) ([]TransactionResponse, error) {
+	query, args := f.page(f.where())
+	rows, err := r.query("SELECT "+txListColumns+" FROM bc_block_transactions"+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]TransactionResponse, 0)
+	for rows.Next() {
+		transaction := TransactionResponse{}
+		err := rows.Scan(&transaction.Id, &transaction.BlockNumber, &transaction.Hash, &transaction.From, &transaction.To, &transaction.Status, &transaction.ImportTime, &transaction.Input, &transaction.Output, &transaction.HeartRate, &transaction.BreathRate, &transaction.SleepState, &transaction.PersonId, &transaction.ContactName, &transaction.ContactIdentity)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *sqlTransactionRepo) ListRes(f TransactionFilter) ([]TransactionResResponse, error) {
+	query, args := f.page(f.where())
+	rows, err := r.query("SELECT "+txResListColumns+" FROM bc_block_transactions"+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]TransactionResResponse, 0)
+	for rows.Next() {
+		transaction := TransactionResResponse{}
+		err := rows.Scan(&transaction.Id, &transaction.BlockNumber, &transaction.Hash, &transaction.From, &transaction.To, &transaction.Status, &transaction.ImportTime, &transaction.Input, &transaction.Output, &transaction.SleepBreathing, &transaction.HeartChange, &transaction.PersonId, &transaction.ContactName, &transaction.ContactIdentity)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *sqlTransactionRepo) Count(f TransactionFilter) (int, error) {
+	where, args := f.where()
+	var total int
+	err := r.queryRow("SELECT COUNT(*) FROM bc_block_transactions"+where, args, &total)
+	return total, err
+}
+
+func (r *sqlTransactionRepo) ActivityOf(address string) (AccountActivity, error) {
+	a := AccountActivity{}
+	err := r.queryRow(txActivityFromQuery, []interface{}{address}, &a.TotalTxCount, &a.SuccessTxCount, &a.FirstSeenBlock, &a.LastSeenBlock)
+	if err != nil {
+		return a, err
+	}
+	// 作为接收方出现的区块
+	var firstTo, lastTo int
+	err = r.queryRow(txActivityToQuery, []interface{}{address}, &firstTo, &lastTo)
+	if err != nil {
+		return a, err
+	}
+	if firstTo > 0 && (a.FirstSeenBlock == 0 || firstTo < a.FirstSeenBlock) {
+		a.FirstSeenBlock = firstTo
+	}
+	if lastTo > a.LastSeenBlock {
+		a.LastSeenBlock = lastTo
+	}
+	return a, nil
+}
+
+func (r *sqlTransactionRepo) LatestVitals(address string) (*VitalsSummary, error) {
+	v := &VitalsSummary{}
+	err := r.queryRow(txLatestVitalsQuery, []interface{}{address, contractMethodId},
+		&v.Hash, &v.BlockNumber, &v.ImportTime, &v.HeartRate, &v.BreathRate, &v.SleepState, &v.HeartChange, &v.SleepBreathing)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *sqlTransactionRepo) LatestPerson(address string) (*LinkedPerson, error) {
+	p := &LinkedPerson{}
+	err := r.queryRow(txLatestPersonQuery, []interface{}{address}, &p.PersonId, &p.ContactName, &p.ContactIdentity)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *sqlTransactionRepo) ShareCountAt(address string, blockNum int) (int, error) {
+	var shareNum int
+	err := r.queryRow(txShareCountAtQuery, []interface{}{contractMethodId, address, blockNum}, &shareNum)
+	return shareNum, err
+}
+
+func (r *sqlTransactionRepo) Get(hash string) (*TransactionDetail, error) {
+	d := &TransactionDetail{}
+	var decodeInput, decodeOutput sql.NullString
+	err := r.queryRow(txGetQuery, []interface{}{hash},
+		&d.BlockNumber, &d.Hash, &d.From, &d.To, &d.Input, &decodeInput, &d.MethodId, &d.Output, &decodeOutput, &d.Status, &d.GasUsed, &d.ImportTime, &d.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if decodeInput.String != "" {
+		d.DecodeInput = json.RawMessage(decodeInput.String)
+	}
+	if decodeOutput.String != "" {
+		d.DecodeOutput = json.RawMessage(decodeOutput.String)
+	}
+	return d, nil
+}
+
+func (r *sqlTransactionRepo) ListByBlock(blockNum int) ([]BlockTransaction, error) {
+	rows, err := r.query(txListByBlockQuery, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]BlockTransaction, 0)
+	for rows.Next() {
+		tx := BlockTransaction{}
+		err := rows.Scan(&tx.Hash, &tx.From, &tx.To, &tx.Input, &tx.MethodId, &tx.Status, &tx.GasUsed, &tx.ImportTime)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+func (r *sqlTransactionRepo) Totals() (TransactionTotals, error) {
+	t := TransactionTotals{}
+	err := r.queryRow(txTotalsQuery, nil, &t.Count, &t.Success, &t.Failed, &t.AvgGasUsed, &t.FirstImportTime, &t.LastImportTime, &t.Blocks)
+	return t, err
+}
+
+func (r *sqlTransactionRepo) Daily(since int64) ([]DailyStats, error) {
+	rows, err := r.query(txDailyQuery, contractMethodId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make([]DailyStats, 0)
+	for rows.Next() {
+		d := DailyStats{}
+		err := rows.Scan(&d.Day, &d.Transactions, &d.ShareData, &d.Success, &d.Failed, &d.AvgGasUsed)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	return days, rows.Err()
+}
+
+func (r *sqlTransactionRepo) Export(f TransactionFilter, fn func(*TransactionExport) error) error {
+	where, args := f.where()
+	order := "DESC"
+	if f.Ascending {
+		order = "ASC"
+	}
+	rows, err := r.query("SELECT "+txExportColumns+" FROM bc_block_transactions"+where+" ORDER BY block_num "+order+", id "+order, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tx := &TransactionExport{}
+	for rows.Next() {
+		var decodeInput sql.NullString
+		err := rows.Scan(&tx.BlockNumber, &tx.Hash, &tx.From, &tx.To, &tx.MethodId, &tx.Status, &tx.GasUsed, &tx.ImportTime, &decodeInput,
+			&tx.HeartRate, &tx.BreathRate, &tx.SleepState, &tx.HeartChange, &tx.SleepBreathing, &tx.PersonId, &tx.ContactName, &tx.ContactIdentity)
+		if err != nil {
+			return err
+		}
+		tx.DecodeInput = nil
+		if decodeInput.String != "" {
+			tx.DecodeInput = json.RawMessage(decodeInput.String)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanIndexedTransaction(rows *sql.Rows) (IndexedTransaction, error) {
+	tx := IndexedTransaction{}
+	var decodeInput, decodeOutput sql.NullString
+	err := rows.Scan(&tx.Id, &tx.BlockNumber, &tx.Hash, &tx.From, &tx.To, &tx.Input, &decodeInput, &tx.MethodId, &tx.Output, &decodeOutput,
+		&tx.Status, &tx.GasUsed, &tx.ImportTime, &tx.HeartRate, &tx.BreathRate, &tx.SleepState, &tx.HeartChange, &tx.SleepBreathing,
+		&tx.PersonId, &tx.ContactName, &tx.ContactIdentity)
+	if decodeInput.String != "" {
+		tx.DecodeInput = json.RawMessage(decodeInput.String)
+	}
+	if decodeOutput.String != "" {
+		tx.DecodeOutput = json.RawMessage(decodeOutput.String)
+	}
+	return tx, err
+}
+
+// queryIndexed 执行查询并逐行回调，IN 条件的长度随批量变化，不进入语句缓存
+func (r *sqlTransactionRepo) queryIndexed(query string, args []interface{}, fn func(tx IndexedTransaction)) error {
+	rows, err := r.s.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tx, err := scanIndexedTransaction(rows)
+		if err != nil {
+			return err
+		}
+		fn(tx)
+	}
+	return rows.Err()
+}
+
+func (r *sqlTransactionRepo) Query(f TransactionFilter) ([]IndexedTransaction, error) {
+	query, args := f.page(f.where())
+	transactions := make([]IndexedTransaction, 0)
+	err := r.queryIndexed("SELECT "+txIndexedColumns+" FROM bc_block_transactions"+query, args, func(tx IndexedTransaction) {
+		transactions = append(transactions, tx)
+	})
+	return transactions, err
+}
+
+func (r *sqlTransactionRepo) GetMany(hashes []string) (map[string]IndexedTransaction, error) {
+	result := make(map[string]IndexedTransaction, len(hashes))
+	if len(hashes) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(hashes))
+	for i, hash := range hashes {
+		args[i] = hash
+	}
+	err := r.queryIndexed("SELECT "+txIndexedColumns+" FROM bc_block_transactions WHERE trans_hash IN ("+placeholders(len(args))+")", args, func(tx IndexedTransaction) {
+		result[tx.Hash] = tx
+	})
+	return result, err
+}
+
+func (r *sqlTransactionRepo) ListByBlocks(numbers []int) (map[int][]IndexedTransaction, error) {
+	result := make(map[int][]IndexedTransaction, len(numbers))
+	if len(numbers) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(numbers))
+	for i, number := range numbers {
+		args[i] = number
+	}
+	err := r.queryIndexed("SELECT "+txIndexedColumns+" FROM bc_block_transactions WHERE block_num IN ("+placeholders(len(args))+") ORDER BY id ASC", args, func(tx IndexedTransaction) {
+		result[tx.BlockNumber] = append(result[tx.BlockNumber], tx)
+	})
+	return result, err
+}
+
+// listLatest 按 column 分组，每组取最新的 limit 条交易。使用 ROW_NUMBER 窗口函数，
+// 需要 MariaDB 10.2、MySQL 8.0、SQLite 3.25 或 PostgreSQL 以上版本
+func (r *sqlTransactionRepo) listLatest(column string, keys []interface{}, vitals bool, limit int, fn func(tx IndexedTransaction)) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	where := column + " IN (" + placeholders(len(keys)) + ")"
+	args := append(make([]interface{}, 0, len(keys)+2), keys...)
+	if vitals {
+		where += " AND method_id = ? AND `status` = 0"
+		args = append(args, contractMethodId)
+	}
+	query := "SELECT " + txIndexedColumns + " FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY " + column + " ORDER BY block_num DESC, id DESC) AS rn " +
+		"FROM bc_block_transactions WHERE " + where + ") t WHERE rn <= ? ORDER BY block_num DESC, id DESC"
+	return r.queryIndexed(query, append(args, limit), fn)
+}
+
+func (r *sqlTransactionRepo) ListBySenders(addresses []string, vitals bool, limit int) (map[string][]IndexedTransaction, error) {
+	result := make(map[string][]IndexedTransaction, len(addresses))
+	keys := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		keys[i] = address
+	}
+	err := r.listLatest("`from`", keys, vitals, limit, func(tx IndexedTransaction) {
+		result[tx.From] = append(result[tx.From], tx)
+	})
+	return result, err
+}
+
+func (r *sqlTransactionRepo) ListByPersons(ids []int, vitals bool, limit int) (map[int][]IndexedTransaction, error) {
+	result := make(map[int][]IndexedTransaction, len(ids))
+	keys := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = id
+	}
+	err := r.listLatest("person_id", keys, vitals, limit, func(tx IndexedTransaction) {
+		result[tx.PersonId] = append(result[tx.PersonId], tx)
+	})
+	return result, err
+}
+
+func (r *sqlTransactionRepo) PersonsOf(addresses []string) (map[string]LinkedPerson, error) {
+	result := make(map[string]LinkedPerson, len(addresses))
+	keys := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		keys[i] = address
+	}
+	err := r.latestPersons("`from`", keys, func(address string, p LinkedPerson) {
+		result[address] = p
+	})
+	return result, err
+}
+
+func (r *sqlTransactionRepo) Persons(ids []int) (map[int]LinkedPerson, error) {
+	result := make(map[int]LinkedPerson, len(ids))
+	keys := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = id
+	}
+	err := r.latestPersons("person_id", keys, func(address string, p LinkedPerson) {
+		result[p.PersonId] = p
+	})
+	return result, err
+}
+
+// latestPersons 按 column 分组取最近一条带 person_id 的交易中的人员信息
+func (r *sqlTransactionRepo) latestPersons(column string, keys []interface{}, fn func(address string, p LinkedPerson)) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	rows, err := r.s.Query("SELECT `from`, person_id, contact_name, contact_identity FROM (SELECT `from`, person_id, contact_name, contact_identity, "+
+		"ROW_NUMBER() OVER (PARTITION BY "+column+" ORDER BY id DESC) AS rn FROM bc_block_transactions WHERE "+column+" IN ("+placeholders(len(keys))+") AND person_id > 0) t WHERE rn = 1", keys...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address string
+		p := LinkedPerson{}
+		if err := rows.Scan(&address, &p.PersonId, &p.ContactName, &p.ContactIdentity); err != nil {
+			return err
+		}
+		fn(address, p)
+	}
+	return rows.Err()
+}
+
+func (r *sqlTransactionRepo) SendersOf(ids []int) (map[int][]string, error) {
+	result := make(map[int][]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.s.Query("SELECT DISTINCT person_id, `from` FROM bc_block_transactions WHERE person_id IN ("+placeholders(len(args))+") ORDER BY person_id, `from`", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var address string
+		if err := rows.Scan(&id, &address); err != nil {
+			return nil, err
+		}
+		result[id] = append(result[id], address)
+	}
+	return result, rows.Err()
+}
+
+const (
+	accountGetQuery          = "SELECT address, balance, cred, share_num FROM bc_block_account WHERE address = ?"
+	accountAllQuery          = "SELECT address, balance, cred, share_num FROM bc_block_account"
+	accountCountQuery        = "SELECT COUNT(*) FROM bc_block_account"
+	accountHistoryQuery      = "SELECT address, block_num, old_balance, new_balance, old_cred, new_cred, old_share_num, new_share_num, created_at FROM bc_account_history WHERE address = ? AND created_at >= ? AND created_at < ? ORDER BY created_at ASC, id ASC LIMIT ?"
+	accountStateBeforeQuery  = "SELECT new_balance, new_cred, new_share_num FROM bc_account_history WHERE address = ? AND block_num <= ? ORDER BY block_num DESC, id DESC LIMIT 1"
+	accountStateAfterQuery   = "SELECT old_balance, old_cred, old_share_num FROM bc_account_history WHERE address = ? AND block_num > ? ORDER BY block_num ASC, id ASC LIMIT 1"
+	accountRegisteredQuery   = "SELECT finished_at, tx_hash FROM bc_register_audit WHERE address = ? AND result IN (?, ?) ORDER BY id DESC LIMIT 1"
+	accountLatestRegJobQuery = "SELECT id, status FROM bc_register_job WHERE address = ? ORDER BY id DESC LIMIT 1"
+	blockMaxNumQuery         = "SELECT COALESCE(MAX(block_num), 0) FROM bc_block_number"
+	blockGetQuery            = "SELECT block_num, block_hash FROM bc_block_number WHERE block_num = ?"
+	blockGetByHashQuery      = "SELECT block_num, block_hash FROM bc_block_number WHERE block_hash = ?"
+	blockCountQuery          = "SELECT COUNT(*) FROM bc_block_number"
+	blockListQuery           = "SELECT block_num, block_hash FROM bc_block_number ORDER BY block_num DESC LIMIT ?"
+	blockListBeforeQuery     = "SELECT block_num, block_hash FROM bc_block_number WHERE block_num < ? ORDER BY block_num DESC LIMIT ?"
+	blockListAfterQuery      = "SELECT block_num, block_hash FROM bc_block_number WHERE block_num > ? ORDER BY block_num ASC LIMIT ?"
+)
+
+type sqlAccountRepo struct {
+	*stmtCache
+}
+
+func newSQLAccountRepo(s *SQL) *sqlAccountRepo {
+	r := &sqlAccountRepo{newStmtCache(s)}
+	r.prepare(accountGetQuery, accountAllQuery, accountCountQuery, accountHistoryQuery,
+		accountStateBeforeQuery, accountStateAfterQuery, accountRegisteredQuery, accountLatestRegJobQuery)
+	return r
+}
+
+func (r *sqlAccountRepo) Get(address string) (*AccountResponse, error) {
+	a := &AccountResponse{}
+	err := r.queryRow(accountGetQuery, []interface{}{address}, &a.Address, &a.Balance, &a.Cred, &a.ShareNnum)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *sqlAccountRepo) All() ([]AccountResponse, error) {
+	rows, err := r.query(accountAllQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]AccountResponse, 0)
+	for rows.Next() {
+		a := AccountResponse{}
+		if err := rows.Scan(&a.Address, &a.Balance, &a.Cred, &a.ShareNnum); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (r *sqlAccountRepo) Count() (int, error) {
+	var total int
+	err := r.queryRow(accountCountQuery, nil, &total)
+	return total, err
+}
+
+// Ranking 按 score 降序、address 升序（保证同分时顺序稳定）分页查询排行榜。
+// 排行榜语句随 sort/window 组合变化，首次使用时预编译
+func (r *sqlAccountRepo) Ranking(opts RankingOptions, offset int, limit int) ([]AccountResponse, error) {
+	query, args := rankingQuery(opts)
+	rows, err := r.query("SELECT address, balance, cred, share_num, score FROM ("+query+") r ORDER BY score DESC, address ASC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]AccountResponse, 0)
+	for rows.Next() {
+		account := AccountResponse{}
+		err := rows.Scan(&account.Address, &account.Balance, &account.Cred, &account.ShareNnum, &account.Score)
+		if err != nil {
+			return nil, err
+		}
+		account.Rank = offset + len(accounts) + 1
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (r *sqlAccountRepo) Rank(opts RankingOptions, address string) (int, error) {
+	query, args := rankingQuery(opts)
+	var score int
+	err := r.queryRow("SELECT score FROM ("+query+") r WHERE address = ?", append(args, address), &score)
+	if err != nil {
+		return 0, err
+	}
+	var ahead int
+	err = r.queryRow("SELECT COUNT(*) FROM ("+query+") r WHERE score > ? OR (score = ? AND address < ?)",
+		append(args, score, score, address), &ahead)
+	if err != nil {
+		return 0, err
+	}
+	return ahead + 1, nil
+}
+
+func (r *sqlAccountRepo) History(address string, start int64, end int64, limit int) ([]AccountHistory, error) {
+	rows, err := r.query(accountHistoryQuery, address, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]AccountHistory, 0)
+	for rows.Next() {
+		h := AccountHistory{}
+		err := rows.Scan(&h.Address, &h.BlockNum, &h.OldBalance, &h.NewBalance, &h.OldCred, &h.NewCred, &h.OldShareNum, &h.NewShareNum, &h.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+// StateAt 取 blockNum 及之前最后一次变化的新值；没有则取之后第一次变化的旧值
+func (r *sqlAccountRepo) StateAt(address string, blockNum int) (*AccountStateResponse, error) {
+	state := &AccountStateResponse{Address: address, BlockNum: blockNum, Source: stateSourceHistory}
+	err := r.queryRow(accountStateBeforeQuery, []interface{}{address, blockNum}, &state.Balance, &state.Cred, &state.ShareNnum)
+	if err == nil {
+		return state, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+	err = r.queryRow(accountStateAfterQuery, []interface{}{address, blockNum}, &state.Balance, &state.Cred, &state.ShareNnum)
+	if err == sql.ErrNoRows {
+		return nil, errStateNotAvailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (r *sqlAccountRepo) GetMany(addresses []string) (map[string]AccountResponse, error) {
+	result := make(map[string]AccountResponse, len(addresses))
+	if len(addresses) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(addresses))
+	for i, address := range addresses {
+		args[i] = address
+	}
+	rows, err := r.s.Query("SELECT address, balance, cred, share_num FROM bc_block_account WHERE address IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := AccountResponse{}
+		if err := rows.Scan(&a.Address, &a.Balance, &a.Cred, &a.ShareNnum); err != nil {
+			return nil, err
+		}
+		result[a.Address] = a
+	}
+	return result, rows.Err()
+}
+
+func (r *sqlAccountRepo) Registration(address string) (AccountRegistration, error) {
+	reg := AccountRegistration{}
+	err := r.queryRow(accountRegisteredQuery, []interface{}{address, "Authorization successful", "Account already authorized"},
+		&reg.RegisteredAt, &reg.TxHash)
+	if err == nil {
+		reg.Registered = true
+	} else if err != sql.ErrNoRows {
+		return reg, err
+	}
+	err = r.queryRow(accountLatestRegJobQuery, []interface{}{address}, &reg.JobId, &reg.JobStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return reg, err
+	}
+	return reg, nil
+}
+
+type sqlBlockRepo struct {
+	*stmtCache
+}
+
+func newSQLBlockRepo(s *SQL) *sqlBlockRepo {
+	r := &sqlBlockRepo{newStmtCache(s)}
+	r.prepare(blockMaxNumQuery, blockGetQuery, blockGetByHashQuery, blockCountQuery, blockListQuery, blockListBeforeQuery, blockListAfterQuery)
+	return r
+}
+
+func (r *sqlBlockRepo) MaxBlockNum() (int, error) {
+	var blockNum int
+	err := r.queryRow(blockMaxNumQuery, nil, &blockNum)
+	return blockNum, err
+}
+
+func (r *sqlBlockRepo) Get(number int) (*BlockDetail, error) {
+	b := &BlockDetail{}
+	err := r.queryRow(blockGetQuery, []interface{}{number}, &b.Number, &b.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *sqlBlockRepo) GetByHash(hash string) (*BlockDetail, error) {
+	b := &BlockDetail{}
+	err := r.queryRow(blockGetByHashQuery, []interface{}{hash}, &b.Number, &b.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *sqlBlockRepo) Count() (int, error) {
+	var count int
+	err := r.queryRow(blockCountQuery, nil, &count)
+	return count, err
+}
+
+func (r *sqlBlockRepo) List(before int, limit int) ([]BlockDetail, error) {
+	var rows *sql.Rows
+	var err error
+	if before > 0 {
+		rows, err = r.query(blockListBeforeQuery, before, limit)
+	} else {
+		rows, err = r.query(blockListQuery, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanBlocks(rows)
+}
+
+func (r *sqlBlockRepo) ListAfter(after int, limit int) ([]BlockDetail, error) {
+	rows, err := r.query(blockListAfterQuery, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlocks(rows)
+}
+
+func scanBlocks(rows *sql.Rows) ([]BlockDetail, error) {
+	defer rows.Close()
+	blocks := make([]BlockDetail, 0)
+	for rows.Next() {
+		b := BlockDetail{}
+		if err := rows.Scan(&b.Number, &b.Hash); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+func (r *sqlBlockRepo) GetMany(numbers []int) (map[int]BlockDetail, error) {
+	result := make(map[int]BlockDetail, len(numbers))
+	if len(numbers) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(numbers))
+	for i, number := range numbers {
+		args[i] = number
+	}
+	rows, err := r.s.Query("SELECT block_num, block_hash FROM bc_block_number WHERE block_num IN ("+placeholders(len(args))+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b := BlockDetail{}
+		if err := rows.Scan(&b.Number, &b.Hash); err != nil {
+			return nil, err
+		}
+		result[b.Number] = b
+	}
+	return result, rows.Err()
+}
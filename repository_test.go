@@ -0,0 +1,362 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testAddressA = "0x00000000000000000000000000000000000000aa"
+	testAddressB = "0x00000000000000000000000000000000000000bb"
+	testAddressC = "0x00000000000000000000000000000000000000cc"
+)
+
+// newTestSQL 在临时目录中创建并迁移 SQLite 数据库
+func newTestSQL(t *testing.T) *SQL {
+	t.Helper()
+	saved := dbTablePrefix
+	dbTablePrefix = "bc_"
+	t.Cleanup(func() { dbTablePrefix = saved })
+
+	store := &sqliteStorage{path: filepath.Join(t.TempDir(), "bc.db")}
+	db, err := store.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return &SQL{db: db, store: store}
+}
+
+func mustExec(t *testing.T, s *SQL, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := s.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// seedTransactions 写入区块 1-3 和 5 笔交易
+func seedTransactions(t *testing.T, s *SQL) {
+	t.Helper()
+	for i, hash := range []string{"0xb1", "0xb2", "0xb3"} {
+		mustExec(t, s, "INSERT INTO bc_block_number (block_num, block_hash) VALUES (?, ?)", i+1, hash)
+	}
+	txs := []struct {
+		block    int
+		hash     string
+		from, to string
+		status   int
+		time     int64
+		person   int
+	}{
+		{1, "0x01", testAddressA, testAddressC, 0, 1000, 0},
+		{1, "0x02", testAddressB, testAddressC, 0, 2000, 0},
+		{2, "0x03", testAddressA, testAddressB, 1, 3000, 0},
+		{3, "0x04", testAddressA, testAddressC, 0, 4000, 7},
+		{3, "0x05", testAddressC, testAddressA, 0, 5000, 0},
+	}
+	for _, tx := range txs {
+		mustExec(t, s, "INSERT INTO bc_block_transactions (block_num, trans_hash, `from`, `to`, method_id, `status`, import_time, person_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			tx.block, tx.hash, tx.from, tx.to, contractMethodId, tx.status, tx.time, tx.person)
+	}
+}
+
+func transactionHashes(list []TransactionResponse) []string {
+	hashes := make([]string, len(list))
+	for i, tx := range list {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+func TestTransactionRepoList(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	repo := newSQLTransactionRepo(s)
+	defer repo.Close()
+
+	success := 0
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		want   []string
+	}{
+		{"from", TransactionFilter{From: testAddressA}, []string{"0x04", "0x03", "0x01"}},
+		{"to", TransactionFilter{To: testAddressC}, []string{"0x04", "0x02", "0x01"}},
+		{"party", TransactionFilter{Party: testAddressA}, []string{"0x05", "0x04", "0x03", "0x01"}},
+		{"status", TransactionFilter{From: testAddressA, Status: &success}, []string{"0x04", "0x01"}},
+		{"person", TransactionFilter{PersonId: 7}, []string{"0x04"}},
+		{"block range", TransactionFilter{Party: testAddressA, BlockStart: 2, BlockEnd: 2}, []string{"0x03"}},
+		{"time range", TransactionFilter{Start: 2000, End: 4000}, []string{"0x03", "0x02"}},
+		{"ascending", TransactionFilter{From: testAddressA, Ascending: true}, []string{"0x01", "0x03", "0x04"}},
+		{"offset", TransactionFilter{Party: testAddressA, Offset: 1}, []string{"0x04", "0x03", "0x01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.filter
+			f.Limit = 10
+			list, err := repo.List(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertAddresses(t, transactionHashes(list), tt.want...)
+			total, err := repo.Count(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Offset == 0 && total != len(tt.want) {
+				t.Errorf("Count = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestTransactionRepoGet(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	repo := newSQLTransactionRepo(s)
+	defer repo.Close()
+
+	d, err := repo.Get("0x04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.BlockNumber != 3 || d.BlockHash != "0xb3" || d.From != testAddressA || d.To != testAddressC {
+		t.Errorf("Get = %+v", d)
+	}
+	if _, err := repo.Get("0xff"); err != sql.ErrNoRows {
+		t.Errorf("Get missing: err = %v, want sql.ErrNoRows", err)
+	}
+
+	block, err := repo.ListByBlock(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block) != 2 || block[0].Hash != "0x04" || block[1].Hash != "0x05" {
+		t.Errorf("ListByBlock(3) = %+v", block)
+	}
+
+	shareNum, err := repo.ShareCountAt(testAddressA, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 区块 2 的交易失败，不计入
+	if shareNum != 1 {
+		t.Errorf("ShareCountAt(A, 2) = %d, want 1", shareNum)
+	}
+	if shareNum, _ = repo.ShareCountAt(testAddressA, 3); shareNum != 2 {
+		t.Errorf("ShareCountAt(A, 3) = %d, want 2", shareNum)
+	}
+
+	many, err := repo.GetMany([]string{"0x01", "0x05", "0xff"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(many) != 2 || many["0x05"].BlockNumber != 3 {
+		t.Errorf("GetMany = %+v", many)
+	}
+	byBlock, err := repo.ListByBlocks([]int{1, 2, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byBlock[1]) != 2 || len(byBlock[2]) != 1 || len(byBlock[9]) != 0 {
+		t.Errorf("ListByBlocks = %+v", byBlock)
+	}
+}
+
+func TestAccountRepo(t *testing.T) {
+	s := newTestSQL(t)
+	accounts := []AccountResponse{
+		{Address: testAddressA, Balance: 10, Cred: 1, ShareNnum: 1},
+		{Address: testAddressB, Balance: 20, Cred: 2, ShareNnum: 0},
+		{Address: testAddressC, Balance: 20, Cred: 0, ShareNnum: 3},
+	}
+	for _, a := range accounts {
+		mustExec(t, s, "INSERT INTO bc_block_account (address, balance, cred, share_num) VALUES (?, ?, ?, ?)", a.Address, a.Balance, a.Cred, a.ShareNnum)
+	}
+	repo := newSQLAccountRepo(s)
+	defer repo.Close()
+
+	a, err := repo.Get(testAddressB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *a != accounts[1] {
+		t.Errorf("Get = %+v, want %+v", a, accounts[1])
+	}
+	if _, err := repo.Get("0x0"); err != sql.ErrNoRows {
+		t.Errorf("Get missing: err = %v, want sql.ErrNoRows", err)
+	}
+
+	opts, err := newRankingOptions("balance", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := repo.Ranking(opts, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := make([]string, len(list))
+	for i, a := range list {
+		addresses[i] = a.Address
+	}
+	// 分数相同时按地址升序
+	assertAddresses(t, addresses, testAddressB, testAddressC, testAddressA)
+	page, err := repo.Ranking(opts, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Address != testAddressC || page[0].Rank != 2 || page[0].Score != 20 {
+		t.Errorf("Ranking(1, 1) = %+v", page)
+	}
+	for i, address := range addresses {
+		rank, err := repo.Rank(opts, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank != i+1 {
+			t.Errorf("Rank(%s) = %d, want %d", address, rank, i+1)
+		}
+	}
+}
+
+func TestAccountRepoStateAt(t *testing.T) {
+	s := newTestSQL(t)
+	s.insertAccountHistory(AccountHistory{Address: testAddressA, BlockNum: 5, OldBalance: 0, NewBalance: 10})
+	s.insertAccountHistory(AccountHistory{Address: testAddressA, BlockNum: 8, OldBalance: 10, NewBalance: 15, OldShareNum: 0, NewShareNum: 1})
+	repo := newSQLAccountRepo(s)
+	defer repo.Close()
+
+	tests := []struct {
+		block    int
+		balance  int
+		shareNum int
+	}{
+		{3, 0, 0},
+		{5, 10, 0},
+		{6, 10, 0},
+		{8, 15, 1},
+		{100, 15, 1},
+	}
+	for _, tt := range tests {
+		state, err := repo.StateAt(testAddressA, tt.block)
+		if err != nil {
+			t.Fatalf("StateAt(%d): %v", tt.block, err)
+		}
+		if state.Balance != tt.balance || state.ShareNnum != tt.shareNum || state.BlockNum != tt.block {
+			t.Errorf("StateAt(%d) = %+v", tt.block, state)
+		}
+	}
+	if _, err := repo.StateAt(testAddressB, 1); err != errStateNotAvailable {
+		t.Errorf("StateAt without history: err = %v, want errStateNotAvailable", err)
+	}
+
+	history, err := repo.History(testAddressA, 0, 1<<62, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("History = %+v", history)
+	}
+}
+
+// TestMixedCaseAddress 大小写混合的地址按小写写入和查询，PostgreSQL 的 varchar 比较区分大小写
+func TestMixedCaseAddress(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	mixed := "0x" + strings.ToUpper(testAddressA[2:])
+
+	s.synAddAddress(mixed)
+	s.insertAccountHistory(AccountHistory{Address: mixed, BlockNum: 1, NewBalance: 10})
+	job := &RegisterJob{Address: mixed}
+	if _, err := s.enqueueRegisterJob(job); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		"SELECT address FROM bc_block_account",
+		"SELECT address FROM bc_account_history",
+		"SELECT address FROM bc_register_job",
+		"SELECT active_address FROM bc_register_job",
+	} {
+		var address string
+		if err := s.QueryRow(query).Scan(&address); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if address != testAddressA {
+			t.Errorf("%s = %s, want %s", query, address, testAddressA)
+		}
+	}
+
+	f, _, err := transactionFilterFromValues(url.Values{"party": {mixed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Party != testAddressA {
+		t.Errorf("filter party = %s, want %s", f.Party, testAddressA)
+	}
+	// 比较时忽略 SQLite 的 NOCASE，与 PostgreSQL 的行为一致
+	var count int
+	if err := s.QueryRow("SELECT COUNT(*) FROM bc_block_transactions WHERE `from` = ? COLLATE BINARY OR `to` = ? COLLATE BINARY", f.Party, f.Party).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("transactions of %s = %d, want 4", f.Party, count)
+	}
+}
+
+func TestBlockRepo(t *testing.T) {
+	s := newTestSQL(t)
+	repo := newSQLBlockRepo(s)
+	defer repo.Close()
+
+	if max, err := repo.MaxBlockNum(); err != nil || max != 0 {
+		t.Errorf("MaxBlockNum on empty table = %d, %v", max, err)
+	}
+	seedTransactions(t, s)
+	if max, err := repo.MaxBlockNum(); err != nil || max != 3 {
+		t.Errorf("MaxBlockNum = %d, %v", max, err)
+	}
+
+	b, err := repo.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash != "0xb2" {
+		t.Errorf("Get(2) = %+v", b)
+	}
+	if b, err = repo.GetByHash("0xb3"); err != nil || b.Number != 3 {
+		t.Errorf("GetByHash = %+v, %v", b, err)
+	}
+	if _, err := repo.Get(9); err != sql.ErrNoRows {
+		t.Errorf("Get missing: err = %v, want sql.ErrNoRows", err)
+	}
+
+	blockNumbers := func(blocks []BlockDetail) []int {
+		numbers := make([]int, len(blocks))
+		for i, b := range blocks {
+			numbers[i] = b.Number
+		}
+		return numbers
+	}
+	if list, err := repo.List(0, 2); err != nil || len(list) != 2 || list[0].Number != 3 || list[1].Number != 2 {
+		t.Errorf("List(0, 2) = %v, %v", blockNumbers(list), err)
+	}
+	if list, err := repo.List(3, 10); err != nil || len(list) != 2 || list[0].Number != 2 {
+		t.Errorf("List(3, 10) = %v, %v", blockNumbers(list), err)
+	}
+	if list, err := repo.ListAfter(1, 10); err != nil || len(list) != 2 || list[0].Number != 2 || list[1].Number != 3 {
+		t.Errorf("ListAfter(1, 10) = %v, %v", blockNumbers(list), err)
+	}
+	many, err := repo.GetMany([]int{1, 3, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(many) != 2 || many[3].Hash != "0xb3" {
+		t.Errorf("GetMany = %+v", many)
+	}
+}
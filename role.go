@@ -0,0 +1,373 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	roleActionGrant  = "grant"
+	roleActionRevoke = "revoke"
+	// roleCheckConcurrency 列出帐户角色时对节点的最大并发请求数
+	roleCheckConcurrency = 8
+)
+
+// RoleAudit 角色授予/撤销记录，对应 bc_role_audit 表
+type RoleAudit struct {
+	Id            int64  `json:"id"`
+	Action        string `json:"action"`
+	Address       string `json:"address"`
+	Actor         string `json:"actor"`
+	RemoteIP      string `json:"remote_ip"`
+	RequestId     string `json:"request_id"`
+	TxHash        string `json:"tx_hash"`
+	ReceiptStatus int    `json:"receipt_status"`
+	Outcome       string `json:"outcome"`
+	Error         string `json:"error"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+type RoleResponse struct {
+	Address string `json:"address"`
+	HasRole bool   `json:"has_role"`
+}
+
+type AccountRoleResponse struct {
+	AccountResponse
+	HasRole bool   `json:"has_role"`
+	Error   string `json:"error,omitempty"`
+}
+
+// roleChecker 解析一次 ABI 后查询多个地址的角色
+type roleChecker struct {
+	contractAbi abi.ABI
+	method      abi.Method
+}
+
+func newRoleChecker() (*roleChecker, error) {
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return nil, err
+	}
+	method, ok := contractAbi.Methods[roleQueryMethod]
+	if !ok {
+		return nil, fmt.Errorf("method %s not found in CONTRACT_ABI", roleQueryMethod)
+	}
+	return &roleChecker{contractAbi: contractAbi, method: method}, nil
+}
+
+// synAccountHasRole 通过 call 读取 Cred 合约中地址的角色
+func synAccountHasRole(address string) (bool, error) {
+	c, err := newRoleChecker()
+	if err != nil {
+		return false, err
+	}
+	return c.hasRole(address)
+}
+
+func (c *roleChecker) hasRole(address string) (bool, error) {
+	data, err := c.contractAbi.Pack(roleQueryMethod, common.HexToAddress(address))
+	if err != nil {
+		return false, err
+	}
+
+	result, err := rpcCall("call", contractAddress, hexutil.Encode(data))
+	if err != nil {
+		return false, err
+	}
+	var receipt TransactionReceipt
+	err = json.Unmarshal(result, &receipt)
+	if err != nil {
+		return false, err
+	}
+	output, err := hexutil.Decode(receipt.Output)
+	if err != nil {
+		return false, err
+	}
+	values, err := c.method.Outputs.Unpack(output)
+	if err != nil {
+		return false, err
+	}
+	if len(values) == 0 {
+		return false, fmt.Errorf("method %s returned no value", roleQueryMethod)
+	}
+	hasRole, ok := values[0].(bool)
+	if !ok {
+		return false, fmt.Errorf("method %s returned %T, want bool", roleQueryMethod, values[0])
+	}
+	return hasRole, nil
+}
+
+func (s *SQL) insertRoleAudit(audit *RoleAudit) {
+	audit.CreatedAt = time.Now().UnixMilli()
+	id, _, err := s.Insert("INSERT INTO bc_role_audit (action, address, actor, remote_ip, request_id, tx_hash, receipt_status, outcome, error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		audit.Action, audit.Address, audit.Actor, audit.RemoteIP, audit.RequestId, audit.TxHash, audit.ReceiptStatus, audit.Outcome, audit.Error, audit.CreatedAt)
+	if err != nil {
+		logger.Error("记录角色审计失败", logKeyAddress, audit.Address, "action", audit.Action, "error", err)
+		return
+	}
+	audit.Id = id
+}
+
+// getRole 查询地址是否拥有 Cred 合约角色
+func getRole(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if !isValidAddress(address) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidAddress, "Invalid address format")
+		return
+	}
+	hasRole, err := synAccountHasRole(address)
+	if err != nil {
+		loggerFromContext(r.Context()).Error("查询角色失败", "error", err)
+		writeError(w, http.StatusBadGateway, errCodeNode, "Error querying contract")
+		return
+	}
+
+	response := ResponseList{
+		Data: RoleResponse{Address: address, HasRole: hasRole},
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// listAccountRoles 分页列出已记录的帐户及其角色状态，每个帐户查询一次节点，需要管理员权限
+func (srv *Server) listAccountRoles(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	checker, err := newRoleChecker()
+	if err != nil {
+		l.Error("加载合约失败", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+
+	queryValues := r.URL.Query()
+	page, err := strconv.Atoi(queryValues.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(queryValues.Get("pagesize"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	s := srv.sql
+
+	offset := (page - 1) * pageSize
+	rows, err := s.Query("SELECT address, balance, cred, share_num FROM bc_block_account ORDER BY id ASC LIMIT ? OFFSET ?", pageSize, offset)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	accounts := make([]AccountRoleResponse, 0)
+	for rows.Next() {
+		account := AccountRoleResponse{}
+		err := rows.Scan(&account.Address, &account.Balance, &account.Cred, &account.ShareNnum)
+		if err != nil {
+			rows.Close()
+			writeDatabaseError(w, r, "Error scanning row data", err)
+			return
+		}
+		accounts = append(accounts, account)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		writeDatabaseError(w, r, "Error scanning row data", err)
+		return
+	}
+
+	// 先释放数据库连接再查询节点，每个帐户写入各自的下标，无需加锁
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, roleCheckConcurrency)
+	for i := range accounts {
+		wg.Add(1)
+		go func(account *AccountRoleResponse) {
+			defer wg.Done()
+			sem <- struct{}{}
+			hasRole, err := checker.hasRole(account.Address)
+			<-sem
+			if err != nil {
+				l.Error("查询角色失败", logKeyAddress, account.Address, "error", err)
+				account.Error = "Error querying contract"
+				return
+			}
+			account.HasRole = hasRole
+		}(&accounts[i])
+	}
+	wg.Wait()
+
+	var total int
+	err = s.QueryRow("SELECT COUNT(*) FROM bc_block_account").Scan(&total)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying total count", err)
+		return
+	}
+
+	response := ResponseList{
+		Data: QueryList{
+			List:     accounts,
+			Page:     page,
+			PageSize: pageSize,
+			Total:    total,
+		},
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// revokeRole 通过控制台调用 Cred 合约撤销地址的角色，需要管理员权限
+func (srv *Server) revokeRole(w http.ResponseWriter, r *http.Request) {
+	l := loggerFromContext(r.Context())
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	var input Input
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		l.Warn("Failed to parse request body", "error", err)
+		writeError(w, http.StatusBadRequest, errCodeBadRequest, "Bad Request")
+		return
+	}
+	if !isValidAddress(input.Address) {
+		writeError(w, http.StatusBadRequest, errCodeInvalidAddress, "Invalid address format")
+		return
+	}
+	l = l.With(logKeyAddress, input.Address)
+
+	result := runConsoleCall(roleRevokeMethod, input.Address)
+	audit := &RoleAudit{
+		Action:        roleActionRevoke,
+		Address:       strings.ToLower(input.Address),
+		Actor:         callerIdentity(r),
+		RemoteIP:      r.RemoteAddr,
+		RequestId:     w.Header().Get("X-Request-Id"),
+		TxHash:        result.TxHash,
+		ReceiptStatus: result.ReceiptStatus,
+	}
+	response := Response{Data: result.TxHash}
+	if result.Err != nil {
+		audit.Outcome = "error"
+		audit.Error = result.Err.Error()
+	} else if result.ReceiptStatus == 0 {
+		audit.Outcome = "Revocation successful"
+		response.Code = 1
+	} else if strings.Contains(result.Output, "account does not have role") {
+		audit.Outcome = "Account not authorized"
+		response.Code = 1
+	} else {
+		audit.Outcome = "Revocation failed"
+	}
+	response.Message = audit.Outcome
+
+	s := srv.sql
+	s.insertRoleAudit(audit)
+	auditLogger.Info("revoke",
+		logKeyRequestId, audit.RequestId,
+		logKeyAddress, audit.Address,
+		logKeyTxHash, audit.TxHash,
+		"actor", audit.Actor,
+		"command", result.Command,
+		"output", result.Output,
+		"outcome", audit.Outcome,
+	)
+	l.Info("revoke", logKeyTxHash, result.TxHash, "outcome", audit.Outcome)
+
+	if result.Err != nil {
+		l.Error("Failed to execute command", "error", result.Err)
+		writeError(w, http.StatusInternalServerError, errCodeInternal, "Internal Server Error")
+		return
+	}
+	if response.Code != 1 {
+		// 交易已上链但执行失败，返回交易哈希便于排查
+		writeErrorData(w, http.StatusUnprocessableEntity, errCodeTransactionReverted, response.Message, result.TxHash)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// listRoleAudits 管理端角色授予/撤销记录，支持 address、action、actor 过滤
+func (srv *Server) listRoleAudits(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	page, err := strconv.Atoi(queryValues.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(queryValues.Get("pagesize"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	for _, field := range []string{"address", "action", "actor"} {
+		if v := queryValues.Get(field); v != "" {
+			if field == "address" {
+				v = strings.ToLower(v)
+			}
+			where = append(where, field+" = ?")
+			args = append(args, v)
+		}
+	}
+	whereSql := ""
+	if len(where) > 0 {
+		whereSql = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	s := srv.sql
+
+	offset := (page - 1) * pageSize
+	rows, err := s.Query("SELECT id, action, address, actor, remote_ip, request_id, tx_hash, receipt_status, outcome, COALESCE(error, ''), created_at FROM bc_role_audit"+whereSql+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, pageSize, offset)...)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying database", err)
+		return
+	}
+	defer rows.Close()
+
+	audits := make([]RoleAudit, 0)
+	for rows.Next() {
+		a := RoleAudit{}
+		err := rows.Scan(&a.Id, &a.Action, &a.Address, &a.Actor, &a.RemoteIP, &a.RequestId, &a.TxHash, &a.ReceiptStatus, &a.Outcome, &a.Error, &a.CreatedAt)
+		if err != nil {
+			writeDatabaseError(w, r, "Error scanning row data", err)
+			return
+		}
+		audits = append(audits, a)
+	}
+
+	var total int
+	err = s.QueryRow("SELECT COUNT(*) FROM bc_role_audit"+whereSql, args...).Scan(&total)
+	if err != nil {
+		writeDatabaseError(w, r, "Error querying total count", err)
+		return
+	}
+
+	response := ResponseList{
+		Data: QueryList{
+			List:     audits,
+			Page:     page,
+			PageSize: pageSize,
+			Total:    total,
+		},
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
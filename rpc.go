@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var rpcClient = &http.Client{Timeout: 30 * time.Second}
+
+// rpcCall 向节点发送 JSON-RPC 请求，返回 result 字段
+func rpcCall(method string, params ...interface{}) (json.RawMessage, error) {
+	requestData := JSONRPCRequest{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  append([]interface{}{"group0", ""}, params...),
+		Id:      1,
+	}
+	requestBody, err := json.Marshal(requestData)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := rpcClient.Post(rpcUrl, "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonResponse JSONRPCResponse
+	err = json.Unmarshal(responseBody, &jsonResponse)
+	if err != nil {
+		return nil, err
+	}
+	if jsonResponse.Error != nil {
+		return nil, fmt.Errorf("rpc %s: %v", method, jsonResponse.Error)
+	}
+	return jsonResponse.Result, nil
+}
+
+// getBlockNumber 查询节点的最新区块高度
+func getBlockNumber() (int, error) {
+	result, err := rpcCall("getBlockNumber")
+	if err != nil {
+		return 0, err
+	}
+	var number int
+	if err := json.Unmarshal(result, &number); err != nil {
+		return 0, fmt.Errorf("invalid block number %s", result)
+	}
+	return number, nil
+}
+
+// getTransactionReceipt 查询交易回执，交易尚未上链时返回 nil
+func getTransactionReceipt(hash string) (*TransactionReceipt, error) {
+	result, err := rpcCall("getTransactionReceipt", hash, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return nil, nil
+	}
+	var receipt TransactionReceipt
+	err = json.Unmarshal(result, &receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// NodeBlock getBlockByNumber/getBlockByHash 返回的区块头和交易列表
+type NodeBlock struct {
+	Hash         string        `json:"hash"`
+	Number       int           `json:"number"`
+	Timestamp    int64         `json:"timestamp"`
+	Sealer       int           `json:"sealer"`
+	SealerList   []string      `json:"sealerList"`
+	GasUsed      string        `json:"gasUsed"`
+	ParentInfo   []ParentInfo  `json:"parentInfo"`
+	TxsRoot      string        `json:"txsRoot"`
+	ReceiptsRoot string        `json:"receiptsRoot"`
+	StateRoot    string        `json:"stateRoot"`
+	Version      int           `json:"version"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+type ParentInfo struct {
+	BlockHash   string `json:"blockHash"`
+	BlockNumber int    `json:"blockNumber"`
+}
+
+// getBlockByNumber 查询区块，onlyHeader 为 true 时不返回交易列表；区块不存在时返回 nil
+func getBlockByNumber(number int, onlyHeader bool) (*NodeBlock, error) {
+	result, err := rpcCall("getBlockByNumber", number, onlyHeader, false)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalNodeBlock(result)
+}
+
+// getBlockByHash 按区块哈希查询区块，区块不存在时返回 nil
+func getBlockByHash(hash string, onlyHeader bool) (*NodeBlock, error) {
+	result, err := rpcCall("getBlockByHash", hash, onlyHeader, false)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalNodeBlock(result)
+}
+
+func unmarshalNodeBlock(result json.RawMessage) (*NodeBlock, error) {
+	if len(result) == 0 || string(result) == "null" {
+		return nil, nil
+	}
+	var block NodeBlock
+	err := json.Unmarshal(result, &block)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// getTransactionByHash 查询交易，交易不存在时返回 nil
+func getTransactionByHash(hash string) (*Transaction, error) {
+	result, err := rpcCall("getTransaction", hash, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return nil, nil
+	}
+	var tx Transaction
+	err = json.Unmarshal(result, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const (
+	searchTypeAccount     = "account"
+	searchTypeTransaction = "transaction"
+	searchTypeBlock       = "block"
+)
+
+var errInvalidSearch = errors.New("query is not an address, hash or block number")
+
+// SearchResult /search 的返回数据，type 为 account、transaction 或 block，path 为对应的详情接口
+type SearchResult struct {
+	Query  string      `json:"query"`
+	Type   string      `json:"type"`
+	Path   string      `json:"path"`
+	Result interface{} `json:"result"`
+}
+
+// search 在本地索引中查找 q：40 位十六进制地址返回帐户概况，64 位十六进制哈希先按交易哈希、再按区块哈希查找，
+// 十进制数字按区块高度查找
+func (srv *Server) search(q string) (*SearchResult, error) {
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case isValidAddress(q):
+		profile, err := srv.accountProfile(q)
+		if err != nil {
+			return nil, err
+		}
+		return &SearchResult{Query: q, Type: searchTypeAccount, Path: "/account/" + q, Result: profile}, nil
+	case isValidHash(q):
+		hash := strings.ToLower(q)
+		d, err := srv.txs.Get(hash)
+		if err == nil {
+			d.Source = "index"
+			d.Logs = make([]DecodedLog, 0)
+			decodeTransactionDetail(contractAbi, d)
+			return &SearchResult{Query: q, Type: searchTypeTransaction, Path: "/tx/" + hash, Result: d}, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+		b, err := srv.blocks.GetByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		return srv.blockSearchResult(contractAbi, q, b)
+	}
+	number, err := strconv.Atoi(q)
+	if err != nil || number < 0 {
+		return nil, errInvalidSearch
+	}
+	b, err := srv.blocks.Get(number)
+	if err != nil {
+		return nil, err
+	}
+	return srv.blockSearchResult(contractAbi, q, b)
+}
+
+func (srv *Server) blockSearchResult(contractAbi abi.ABI, q string, b *BlockDetail) (*SearchResult, error) {
+	var err error
+	b.Source = "index"
+	b.Transactions, err = srv.txs.ListByBlock(b.Number)
+	if err != nil {
+		return nil, err
+	}
+	decodeBlockTransactions(contractAbi, b)
+	return &SearchResult{Query: q, Type: searchTypeBlock, Path: "/block/" + strconv.Itoa(b.Number), Result: b}, nil
+}
+
+// handleSearch 处理 /search?q=，供客服粘贴用户提供的地址、交易哈希、区块哈希或区块高度
+func (srv *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Missing query")
+		return
+	}
+	result, err := srv.search(q)
+	if err == errInvalidSearch {
+		writeError(w, http.StatusBadRequest, errCodeInvalidParameter, "Query is not an address, hash or block number")
+		return
+	}
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, errCodeNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		loggerFromContext(r.Context()).Error("Error querying database", "error", err)
+		writeError(w, http.StatusInternalServerError, errCodeDatabase, "Error querying database")
+		return
+	}
+
+	response := ResponseList{
+		Data: result,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Server HTTP 服务，所有处理函数共享同一个连接池和仓储
+type Server struct {
+	sql      *SQL
+	txs      TransactionRepo
+	accounts AccountRepo
+	blocks   BlockRepo
+}
+
+// NewServer 基于共享连接池创建服务，仓储的固定语句在此时预编译
+func NewServer(s *SQL) *Server {
+	return &Server{
+		sql:      s,
+		txs:      newSQLTransactionRepo(s),
+		accounts: newSQLAccountRepo(s),
+		blocks:   newSQLBlockRepo(s),
+	}
+}
+
+func (srv *Server) routes() *http.ServeMux {
+	table := srv.routeTable()
+	if err := validateRoutes(table); err != nil {
+		panic(err)
+	}
+	spec, err := json.Marshal(buildOpenAPI(table))
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	for _, rt := range table {
+		mux.HandleFunc(rt.pattern, withRequestLog(rt.handler))
+	}
+	mux.HandleFunc("/openapi.json", withRequestLog(serveOpenAPI(spec)))
+	mux.HandleFunc("/", withRequestLog(handleNotFound))
+	return mux
+}
+
+// routeTable 所有接口的路由和文档
+func (srv *Server) routeTable() []route {
+	addressParam := apiParam{Name: "address", Required: true}
+	return []route{
+		{"/register", srv.handleRequest, []apiOperation{{
+			Method: "POST", Path: "/register", Summary: "提交注册任务",
+			Body: Input{}, Data: RegisterJob{}, Status: http.StatusAccepted,
+			Errors: []int{http.StatusBadRequest, http.StatusMethodNotAllowed},
+		}}},
+		{"/contract-address", getContractAddress, []apiOperation{{
+			Method: "GET", Path: "/contract-address", Summary: "Cred 合约地址", Data: "",
+		}}},
+		{"/getTransByAddress", srv.getTransByAddress, []apiOperation{{
+			Method: "GET", Path: "/getTransByAddress", Summary: "交易列表（体征字段）",
+			Params: transactionPageParams, Data: TransactionList{}, Items: TransactionResponse{},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/getResByAddress", srv.getResByAddress, []apiOperation{{
+			Method: "GET", Path: "/getResByAddress", Summary: "交易列表（睡眠呼吸、心率变化字段）",
+			Params: transactionPageParams, Data: TransactionList{}, Items: TransactionResResponse{},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/accountRanking", srv.accountRanking, []apiOperation{{
+			Method: "GET", Path: "/accountRanking", Summary: "排行榜",
+			Params: append(append([]apiParam{}, rankingParams...), pageParams...), Data: AccountQueryList{},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/accountRanking/", srv.accountRankByAddress, []apiOperation{{
+			Method: "GET", Path: "/accountRanking/{address}", Summary: "地址在排行榜中的名次和前后名次",
+			Params: append(append([]apiParam{{Name: "address", In: "path"}}, rankingParams...),
+				apiParam{Name: "neighbours", Type: "integer", Description: "前后各返回的帐户数"}),
+			Data: AccountRankResponse{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		}}},
+		{"/register/status", srv.getRegisterStatus, []apiOperation{{
+			Method: "GET", Path: "/register/status", Summary: "按地址或交易哈希查询注册状态",
+			Params: []apiParam{{Name: "address"}, {Name: "tx_hash"}}, Data: RegisterStatus{},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/register/job", srv.getRegisterJob, []apiOperation{{
+			Method: "GET", Path: "/register/job", Summary: "查询注册任务",
+			Params: []apiParam{{Name: "id", Type: "integer", Required: true}}, Data: RegisterJob{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		}}},
+		{"/register/batch", srv.handleBatchRegister, []apiOperation{{
+			Method: "POST", Path: "/register/batch", Summary: "批量提交注册任务",
+			Body: BatchRegisterInput{}, Data: BatchRegisterReport{}, Status: http.StatusAccepted,
+			Errors: []int{http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge},
+		}}},
+		{"/admin/registrations", requireAdmin(srv.listRegistrations), []apiOperation{{
+			Method: "GET", Path: "/admin/registrations", Summary: "注册审计记录", Admin: true,
+			Params: append([]apiParam{{Name: "address"}, {Name: "caller"}, {Name: "result"}, {Name: "tx_hash"},
+				{Name: "start", Type: "integer"}, {Name: "end", Type: "integer"}}, pageParams...),
+			Data: QueryList{}, Items: RegisterAudit{},
+		}}},
+		{"/accountHistory", srv.getAccountHistory, []apiOperation{{
+			Method: "GET", Path: "/accountHistory", Summary: "帐户 balance、cred、share_num 变化历史",
+			Params: []apiParam{addressParam, {Name: "start", Type: "integer"}, {Name: "end", Type: "integer"},
+				{Name: "interval", Description: "降采样区间，如 1h、1d"}},
+			Data: AccountHistoryList{}, Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/account/", srv.getAccount, []apiOperation{{
+			Method: "GET", Path: "/account/{address}", Summary: "帐户概况，带 block 参数时返回该区块高度的状态",
+			Params: []apiParam{{Name: "address", In: "path"}, {Name: "block", Type: "integer"}},
+			OneOf:  []interface{}{AccountProfile{}, AccountStateResponse{}},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway},
+		}}},
+		{"/tx/", srv.getTransaction, []apiOperation{{
+			Method: "GET", Path: "/tx/{hash}", Summary: "交易详情，本地未索引时从节点读取",
+			Params: []apiParam{{Name: "hash", In: "path"}}, Data: TransactionDetail{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway},
+		}}},
+		{"/block/", srv.getBlock, []apiOperation{{
+			Method: "GET", Path: "/block/{numberOrHash}", Summary: "区块详情，本地未索引时从节点读取",
+			Params: []apiParam{{Name: "numberOrHash", In: "path"}}, Data: BlockDetail{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway},
+		}}},
+		{"/search", srv.handleSearch, []apiOperation{{
+			Method: "GET", Path: "/search", Summary: "按地址、交易哈希、区块哈希或区块高度搜索",
+			Params: []apiParam{{Name: "q", Required: true}}, Data: SearchResult{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+		}}},
+		{"/stats", getStats, []apiOperation{{
+			Method: "GET", Path: "/stats", Summary: "链上统计", Data: ChainStats{},
+			Errors: []int{http.StatusServiceUnavailable},
+		}}},
+		{"/export/transactions", requireAdmin(srv.exportTransactions), []apiOperation{{
+			Method: "GET", Path: "/export/transactions", Summary: "导出交易（CSV 或 NDJSON）", Admin: true,
+			Params: exportParams, ContentType: []string{"text/csv", "application/x-ndjson"},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/export/vitals", requireAdmin(srv.exportVitals), []apiOperation{{
+			Method: "GET", Path: "/export/vitals", Summary: "导出体征数据（CSV 或 NDJSON）", Admin: true,
+			Params: exportParams, ContentType: []string{"text/csv", "application/x-ndjson"},
+			Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/fhir/Observation", requireAdmin(srv.exportFHIRObservations), []apiOperation{{
+			Method: "GET", Path: "/fhir/Observation", Summary: "体征数据 FHIR R4 Observation Bundle", Admin: true,
+			Params:      []apiParam{{Name: "person", Type: "integer", Required: true}, {Name: "start", Type: "integer", Required: true}, {Name: "end", Type: "integer", Required: true}},
+			ContentType: []string{fhirContentType}, Errors: []int{http.StatusBadRequest},
+		}}},
+		{"/graphql", srv.handleGraphQL, []apiOperation{
+			{Method: "GET", Path: "/graphql", Summary: "GraphQL 查询（query、operationName、variables 参数）",
+				Params:      []apiParam{{Name: "query", Required: true}, {Name: "operationName"}, {Name: "variables", Description: "JSON 字符串"}},
+				ContentType: []string{"application/json"}, Errors: []int{http.StatusBadRequest}},
+			{Method: "POST", Path: "/graphql", Summary: "GraphQL 查询",
+				Body: graphqlRequest{}, ContentType: []string{"application/json"},
+				Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge}},
+		}},
+		{"/role", getRole, []apiOperation{{
+			Method: "GET", Path: "/role", Summary: "地址是否拥有 Cred 合约角色",
+			Params: []apiParam{addressParam}, Data: RoleResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusBadGateway},
+		}}},
+		{"/roles", requireAdmin(srv.listAccountRoles), []apiOperation{{
+			Method: "GET", Path: "/roles", Summary: "帐户及其角色状态", Admin: true,
+			Params: pageParams, Data: QueryList{}, Items: AccountRoleResponse{},
+		}}},
+		{"/admin/role/revoke", requireAdmin(srv.revokeRole), []apiOperation{{
+			Method: "POST", Path: "/admin/role/revoke", Summary: "撤销地址的角色，data 为交易哈希", Admin: true,
+			Body: Input{}, Data: "",
+			Errors: []int{http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusUnprocessableEntity},
+		}}},
+		{"/admin/role/audits", requireAdmin(srv.listRoleAudits), []apiOperation{{
+			Method: "GET", Path: "/admin/role/audits", Summary: "角色授予/撤销记录", Admin: true,
+			Params: append([]apiParam{{Name: "address"}, {Name: "action"}, {Name: "actor"}}, pageParams...),
+			Data:   QueryList{}, Items: RoleAudit{},
+		}}},
+		{"/log-level", handleLogLevel, []apiOperation{
+			{Method: "GET", Path: "/log-level", Summary: "当前日志级别", Data: ""},
+			{Method: "PUT", Path: "/log-level", Summary: "修改日志级别", Admin: true, Body: LogLevelInput{}, Data: "",
+				Errors: []int{http.StatusBadRequest}},
+		}},
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// 已同步区块的轮询间隔和每次最多推送的区块数
+const (
+	blockWatchInterval = 2 * time.Second
+	blockWatchBatch    = 100
+)
+
+// ServiceError 服务层可预期的错误，Code 为 errCode* 错误码，HTTP 和 gRPC 按 Code 转换为各自的状态码；
+// Err 为需要记录日志的底层错误，不返回给客户端
+type ServiceError struct {
+	Code string
+	Msg  string
+	Err  error
+}
+
+func (e *ServiceError) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// errMissingTransactionSubject 交易列表未指定帐户或人员，HTTP、gRPC 和 GraphQL 共用
+var errMissingTransactionSubject = serviceError(errCodeInvalidParameter, "Missing address, from, to, party or person")
+
+func serviceError(code string, msg string) *ServiceError {
+	return &ServiceError{Code: code, Msg: msg}
+}
+
+func databaseError(msg string, err error) *ServiceError {
+	return &ServiceError{Code: errCodeDatabase, Msg: msg, Err: err}
+}
+
+// asServiceError 非 ServiceError 的错误按内部错误处理
+func asServiceError(err error) *ServiceError {
+	var se *ServiceError
+	if errors.As(err, &se) {
+		return se
+	}
+	return &ServiceError{Code: errCodeInternal, Msg: "Internal Server Error", Err: err}
+}
+
+// normalizePage 页码从 1 开始，每页默认 10 条，最多 100 条
+func normalizePage(page int, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+// RegisterCaller 注册请求的来源，写入注册任务和审计记录
+type RegisterCaller struct {
+	Caller    string
+	RemoteIP  string
+	RequestId string
+}
+
+// submitRegister 校验地址和回调地址后提交到注册任务队列，由 worker 异步执行并等待回执
+func (srv *Server) submitRegister(l *slog.Logger, input Input, caller RegisterCaller) (*RegisterJob, error) {
+	if !isValidAddress(input.Address) {
+		return nil, serviceError(errCodeInvalidAddress, "Invalid address format")
+	}
+	if input.CallbackUrl != "" {
+		if err := checkCallbackUrl(input.CallbackUrl); err != nil {
+			return nil, serviceError(errCodeInvalidParameter, err.Error())
+		}
+	}
+
+	job := &RegisterJob{
+		Address:     input.Address,
+		CallbackUrl: input.CallbackUrl,
+		Caller:      caller.Caller,
+		RemoteIP:    caller.RemoteIP,
+		RequestId:   caller.RequestId,
+	}
+	coalesced, err := srv.sql.enqueueRegisterJob(job)
+	if err != nil {
+		return nil, &ServiceError{Code: errCodeInternal, Msg: "Internal Server Error", Err: fmt.Errorf("提交注册任务失败: %w", err)}
+	}
+	l.Info("提交注册任务", logKeyAddress, input.Address, "job_id", job.Id, "coalesced", coalesced)
+	return job, nil
+}
+
+// transactionPage 交易列表的公共流程，必须指定帐户或人员，不允许遍历整张交易表；游标分页时多取一条判断是否还有下一页，page 模式或 withTotal 时返回总数。
+// list 返回最多 pageSize 条数据，以及查询到的每条数据（可能多一条）的游标
+func (srv *Server) transactionPage(f TransactionFilter, cursorMode bool, withTotal bool, list func(f TransactionFilter, pageSize int) (interface{}, []TransactionCursor, error)) (*TransactionList, error) {
+	if !f.hasSubject() {
+		return nil, errMissingTransactionSubject
+	}
+	pageSize := f.Limit
+	if cursorMode {
+		f.Limit++
+	}
+
+	transactions, cursors, err := list(f, pageSize)
+	if err != nil {
+		return nil, databaseError("Error querying database", err)
+	}
+	result := &TransactionList{
+		List:     transactions,
+		PageSize: pageSize,
+	}
+	if cursorMode {
+		if len(cursors) > pageSize {
+			result.NextCursor = encodeTransactionCursor(cursors[pageSize-1])
+		}
+	} else {
+		result.Page = f.Offset/pageSize + 1
+	}
+
+	if !cursorMode || withTotal {
+		total, err := srv.txs.Count(f)
+		if err != nil {
+			return nil, databaseError("Error querying total count", err)
+		}
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// indexedTransactionPage 按 transactionPage 的流程返回全部字段的交易，供 gRPC 使用
+func (srv *Server) indexedTransactionPage(f TransactionFilter, cursorMode bool, withTotal bool) (*TransactionList, error) {
+	return srv.transactionPage(f, cursorMode, withTotal, func(f TransactionFilter, pageSize int) (interface{}, []TransactionCursor, error) {
+		transactions, err := srv.txs.Query(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		cursors := make([]TransactionCursor, len(transactions))
+		for i, tx := range transactions {
+			cursors[i] = TransactionCursor{BlockNum: tx.BlockNumber, Id: tx.Id}
+		}
+		if len(transactions) > pageSize {
+			transactions = transactions[:pageSize]
+		}
+		return transactions, cursors, nil
+	})
+}
+
+// newRankingOptions 校验 sort（balance、cred、share_num，默认 balance）和 window（all、7d、30d，默认 all）
+func newRankingOptions(sort string, window string) (RankingOptions, error) {
+	opts := RankingOptions{Sort: sort, Window: window}
+	if opts.Sort == "" {
+		opts.Sort = "balance"
+	}
+	if opts.Window == "" {
+		opts.Window = "all"
+	}
+	if !rankingSortKeys[opts.Sort] {
+		return opts, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid sort %q", opts.Sort))
+	}
+	if _, ok := rankingWindows[opts.Window]; !ok {
+		return opts, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid window %q", opts.Window))
+	}
+	return opts, nil
+}
+
+// rankingPage 排行榜的一页。全时段排行榜读取内存，其余从缓存或数据库查询；
+// 同时返回用于 HTTP 缓存的 ETag 和最后修改时间
+func (srv *Server) rankingPage(opts RankingOptions, page int, pageSize int) (result *AccountQueryList, etag string, modified time.Time, err error) {
+	page, pageSize = normalizePage(page, pageSize)
+	offset := (page - 1) * pageSize
+	result = &AccountQueryList{
+		Page:     page,
+		PageSize: pageSize,
+		Sort:     opts.Sort,
+		Window:   opts.Window,
+	}
+	if loaded, version, lastModified := leaderboard.state(); opts.Window == "all" && loaded {
+		etag = fmt.Sprintf(`"lb-%d-%s-%d-%d"`, version, opts.Sort, page, pageSize)
+		modified = lastModified
+		result.List, result.Total = leaderboard.page(opts.Sort, offset, pageSize)
+		return result, etag, modified, nil
+	}
+	entry, err := cachedWindowRanking(srv.accounts, opts, offset, pageSize)
+	if err != nil {
+		return nil, "", time.Time{}, databaseError("Error querying database", err)
+	}
+	etag = fmt.Sprintf(`"rk-%d-%s-%s-%d-%d"`, entry.modified.UnixNano(), opts.Sort, opts.Window, page, pageSize)
+	result.List, result.Total = entry.accounts, entry.total
+	return result, etag, entry.modified, nil
+}
+
+// accountRank 地址在排行榜中的名次和前后 neighbours 名帐户，neighbours 不在 0~50 之间时取 2
+func (srv *Server) accountRank(opts RankingOptions, address string, neighbours int) (*AccountRankResponse, error) {
+	if !isValidAddress(address) {
+		return nil, serviceError(errCodeInvalidAddress, "Invalid address format")
+	}
+	address = strings.ToLower(address)
+	if neighbours < 0 || neighbours > 50 {
+		neighbours = 2
+	}
+
+	var list []AccountResponse
+	var total int
+	if loaded, _, _ := leaderboard.state(); opts.Window == "all" && loaded {
+		rank := leaderboard.rank(opts.Sort, address)
+		if rank == 0 {
+			return nil, serviceError(errCodeNotFound, "Account not found")
+		}
+		offset := neighbourOffset(rank, neighbours)
+		list, total = leaderboard.page(opts.Sort, offset, rank-offset+neighbours)
+	} else {
+		rank, err := srv.accounts.Rank(opts, address)
+		if err == sql.ErrNoRows {
+			return nil, serviceError(errCodeNotFound, "Account not found")
+		}
+		if err != nil {
+			return nil, databaseError("Error querying database", err)
+		}
+		offset := neighbourOffset(rank, neighbours)
+		list, err = srv.accounts.Ranking(opts, offset, rank-offset+neighbours)
+		if err != nil {
+			return nil, databaseError("Error querying database", err)
+		}
+		total, err = srv.accounts.Count()
+		if err != nil {
+			return nil, databaseError("Error querying total count", err)
+		}
+	}
+
+	result := &AccountRankResponse{
+		Sort:       opts.Sort,
+		Window:     opts.Window,
+		Total:      total,
+		Neighbours: make([]AccountResponse, 0),
+	}
+	for _, account := range list {
+		if strings.EqualFold(account.Address, address) {
+			result.Account = account
+			continue
+		}
+		result.Neighbours = append(result.Neighbours, account)
+	}
+	return result, nil
+}
+
+// watchBlocks 按高度升序推送已同步的区块（含区块内的交易），from 为 0 时从当前最新区块之后开始。
+// 区块同步任务可能运行在其他进程中，这里按 blockWatchInterval 轮询 bc_block_number，直到 ctx 结束或 send 返回错误
+func (srv *Server) watchBlocks(ctx context.Context, from int, send func(b *BlockDetail) error) error {
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return &ServiceError{Code: errCodeInternal, Msg: "Internal Server Error", Err: fmt.Errorf("加载合约失败: %w", err)}
+	}
+	after := from - 1
+	if from <= 0 {
+		after, err = srv.blocks.MaxBlockNum()
+		if err != nil {
+			return databaseError("Error querying database", err)
+		}
+	}
+
+	ticker := time.NewTicker(blockWatchInterval)
+	defer ticker.Stop()
+	for {
+		blocks, err := srv.blocks.ListAfter(after, blockWatchBatch)
+		if err != nil {
+			return databaseError("Error querying database", err)
+		}
+		for i := range blocks {
+			b := &blocks[i]
+			b.Source = "index"
+			b.Transactions, err = srv.txs.ListByBlock(b.Number)
+			if err != nil {
+				return databaseError("Error querying database", err)
+			}
+			decodeBlockTransactions(contractAbi, b)
+			if err := send(b); err != nil {
+				return err
+			}
+			after = b.Number
+		}
+		if len(blocks) == blockWatchBatch {
+			// 还有未推送的区块，不等待下一次轮询
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const dayMillis = int64(24 * time.Hour / time.Millisecond)
+
+// TransactionTotals 交易表的汇总数据
+type TransactionTotals struct {
+	Count           int
+	Success         int
+	Failed          int
+	AvgGasUsed      float64
+	FirstImportTime int64
+	LastImportTime  int64
+	Blocks          int // 含交易的区块数
+}
+
+// DailyStats 某一天（UTC）的交易统计，day 为当天零点的毫秒时间戳
+type DailyStats struct {
+	Day          int64   `json:"day"`
+	Date         string  `json:"date"`
+	Transactions int     `json:"transactions"`
+	ShareData    int     `json:"share_data"`
+	Success      int     `json:"success"`
+	Failed       int     `json:"failed"`
+	AvgGasUsed   float64 `json:"avg_gas_used"`
+}
+
+// ChainStats /stats 的返回数据，由后台任务定时计算，updated_at 为计算时间
+type ChainStats struct {
+	TotalBlocks        int          `json:"total_blocks"`
+	TotalTransactions  int          `json:"total_transactions"`
+	RegisteredAccounts int          `json:"registered_accounts"`
+	SuccessCount       int          `json:"success_count"`
+	FailedCount        int          `json:"failed_count"`
+	SuccessRatio       float64      `json:"success_ratio"`
+	AvgGasUsed         float64      `json:"avg_gas_used"`
+	AvgBlockIntervalMs float64      `json:"avg_block_interval_ms"`
+	Daily              []DailyStats `json:"daily"`
+	UpdatedAt          int64        `json:"updated_at"`
+}
+
+var (
+	chainStatsMu sync.RWMutex
+	chainStats   *ChainStats
+)
+
+// initStats 计算统计数据并启动定时刷新，刷新间隔 STATS_REFRESH_MINUTES（默认 10）分钟，
+// 按天统计最近 STATS_DAYS（默认 30）天
+func initStats(srv *Server) {
+	refreshStats(srv)
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Stats.RefreshMinutes) * time.Minute)
+		for {
+			<-ticker.C // 等待计时器触发
+			refreshStats(srv)
+		}
+	}()
+}
+
+func refreshStats(srv *Server) {
+	stats, err := srv.computeStats(cfg.Stats.Days, time.Now())
+	if err != nil {
+		logger.Error("统计数据计算失败", "error", err)
+		return
+	}
+	chainStatsMu.Lock()
+	chainStats = stats
+	chainStatsMu.Unlock()
+	logger.Debug("统计数据已刷新", "total_blocks", stats.TotalBlocks, "total_transactions", stats.TotalTransactions)
+}
+
+// computeStats 汇总区块、交易和帐户数据，daily 从 days 天前的 UTC 零点到今天，没有交易的日期补 0
+func (srv *Server) computeStats(days int, now time.Time) (*ChainStats, error) {
+	stats := &ChainStats{UpdatedAt: now.UnixMilli()}
+	var err error
+	stats.TotalBlocks, err = srv.blocks.Count()
+	if err != nil {
+		return nil, err
+	}
+	stats.RegisteredAccounts, err = srv.accounts.Count()
+	if err != nil {
+		return nil, err
+	}
+	totals, err := srv.txs.Totals()
+	if err != nil {
+		return nil, err
+	}
+	stats.TotalTransactions = totals.Count
+	stats.SuccessCount = totals.Success
+	stats.FailedCount = totals.Failed
+	if totals.Success+totals.Failed > 0 {
+		stats.SuccessRatio = float64(totals.Success) / float64(totals.Success+totals.Failed)
+	}
+	stats.AvgGasUsed = totals.AvgGasUsed
+	if totals.Blocks > 1 {
+		stats.AvgBlockIntervalMs = float64(totals.LastImportTime-totals.FirstImportTime) / float64(totals.Blocks-1)
+	}
+
+	today := now.UnixMilli() / dayMillis * dayMillis
+	since := today - int64(days-1)*dayMillis
+	rows, err := srv.txs.Daily(since)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[int64]DailyStats, len(rows))
+	for _, d := range rows {
+		byDay[d.Day] = d
+	}
+	stats.Daily = make([]DailyStats, 0, days)
+	for day := since; day <= today; day += dayMillis {
+		d := byDay[day]
+		d.Day = day
+		d.Date = time.UnixMilli(day).UTC().Format("2006-01-02")
+		stats.Daily = append(stats.Daily, d)
+	}
+	return stats, nil
+}
+
+// getStats 返回后台任务缓存的统计数据，首次计算尚未成功时返回 503
+func getStats(w http.ResponseWriter, r *http.Request) {
+	chainStatsMu.RLock()
+	stats := chainStats
+	chainStatsMu.RUnlock()
+	if stats == nil {
+		writeError(w, http.StatusServiceUnavailable, errCodeNotReady, "Statistics not ready")
+		return
+	}
+
+	response := ResponseList{
+		Data: stats,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
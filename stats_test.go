@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeNode 启动模拟节点，handle 返回 JSON-RPC 的 result（JSON 字符串）
+func fakeNode(t *testing.T, handle func(method string, params []interface{}) string) {
+	t.Helper()
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%s}`, handle(req.Method, req.Params))
+	}))
+	t.Cleanup(node.Close)
+	saved := rpcUrl
+	rpcUrl = node.URL
+	t.Cleanup(func() { rpcUrl = saved })
+}
+
+func TestComputeStatsAvgBlockInterval(t *testing.T) {
+	s := newTestSQL(t)
+	srv := NewServer(s)
+	stats, err := srv.computeStats(7, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.AvgBlockIntervalMs != 0 {
+		t.Errorf("no transactions: avg = %v", stats.AvgBlockIntervalMs)
+	}
+
+	// 3 个区块的交易 import_time 为 1000~5000
+	seedTransactions(t, s)
+	if stats, err = srv.computeStats(7, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if stats.AvgBlockIntervalMs != 2000 || stats.TotalTransactions != 5 || len(stats.Daily) != 7 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Storage 数据库后端，封装驱动、连接串、建表以及各数据库在 SQL 语法上的差异。
+// 业务语句统一按 MySQL 写法（? 占位符、反引号引用标识符、LIMIT ? OFFSET ?）编写，执行前由 Rebind 转换为后端的写法
+type Storage interface {
+	Name() string
+	Open() (*sql.DB, error)
+	Migrate(db *sql.DB) error
+	Rebind(query string) string
+	// OnConflictDoNothing 为 INSERT 语句追加唯一性冲突时不做任何修改的子句
+	OnConflictDoNothing(query string) string
+	// Insert 执行 INSERT 并返回自增 id，inserted 为 false 表示唯一性冲突未插入
+	Insert(db *sql.DB, query string, args ...interface{}) (id int64, inserted bool, err error)
+}
+
+// newStorage 按 DB_DRIVER 选择数据库后端，默认 mysql（MariaDB），可选 sqlite、postgres
+func newStorage(driver string) (Storage, error) {
+	switch driver {
+	case "", "mysql", "mariadb":
+		return &mysqlStorage{}, nil
+	case "sqlite":
+		return &sqliteStorage{path: dbPath}, nil
+	case "postgres", "postgresql":
+		return &postgresStorage{}, nil
+	}
+	return nil, fmt.Errorf("unsupported DB_DRIVER %q", driver)
+}
+
+// migrateTables 依次执行建表语句，语句中的表名占位符替换为带前缀的表名
+func migrateTables(db *sql.DB, tables [][2]string) error {
+	for _, t := range tables {
+		tableName := dbTablePrefix + t[0]
+		_, err := db.Exec(fmt.Sprintf(t[1], tableName))
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", tableName, err)
+		}
+		logger.Info("加载表成功", "table", tableName)
+	}
+	return nil
+}
+
+func (s *SQL) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.db.Exec(s.store.Rebind(query), args...)
+}
+
+func (s *SQL) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.Query(s.store.Rebind(query), args...)
+}
+
+func (s *SQL) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.db.QueryRow(s.store.Rebind(query), args...)
+}
+
+func (s *SQL) Prepare(query string) (*sql.Stmt, error) {
+	return s.db.Prepare(s.store.Rebind(query))
+}
+
+func (s *SQL) Insert(query string, args ...interface{}) (int64, bool, error) {
+	return s.store.Insert(s.db, s.store.Rebind(query), args...)
+}
+
+// InsertIgnore 插入一行，唯一性冲突时不修改已有数据并返回 inserted 为 false
+func (s *SQL) InsertIgnore(query string, args ...interface{}) (int64, bool, error) {
+	return s.Insert(s.store.OnConflictDoNothing(query), args...)
+}
+
+// execInsert 通过 RowsAffected 和 LastInsertId 实现 Insert，适用于 MySQL 和 SQLite
+func execInsert(db *sql.DB, query string, args ...interface{}) (int64, bool, error) {
+	rs, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, false, err
+	}
+	n, err := rs.RowsAffected()
+	if err != nil || n == 0 {
+		return 0, false, err
+	}
+	id, err := rs.LastInsertId()
+	return id, true, err
+}
+
+// jsonOrNull 空字符串按 NULL 写入，JSON 类型的字段（PostgreSQL 的 JSONB）不接受空字符串
+func jsonOrNull(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// mysqlStorage MariaDB/MySQL 后端，业务语句本身就是 MySQL 写法
+type mysqlStorage struct{}
+
+func (m *mysqlStorage) Name() string {
+	return "mysql"
+}
+
+func (m *mysqlStorage) Open() (*sql.DB, error) {
+	dsn := dbUsername + ":" + dbPassword + "@tcp" + "(" + dbHost + ":" + dbPort + ")/" + dbase
+	return sql.Open("mysql", dsn)
+}
+
+func (m *mysqlStorage) Rebind(query string) string {
+	return query
+}
+
+// OnConflictDoNothing 用 id = id 实现空更新，已存在的行 RowsAffected 为 0
+func (m *mysqlStorage) OnConflictDoNothing(query string) string {
+	return query + " ON DUPLICATE KEY UPDATE id = id"
+}
+
+func (m *mysqlStorage) Insert(db *sql.DB, query string, args ...interface{}) (int64, bool, error) {
+	return execInsert(db, query, args...)
+}
+
+func (m *mysqlStorage) Migrate(db *sql.DB) error {
+	// 创建数据库
+	_, err := db.Exec("CREATE DATABASE IF NOT EXISTS " + dbase)
+	if err != nil {
+		return err
+	}
+
+	// 切换到新创建的数据库
+	_, err = db.Exec("USE " + dbase)
+	if err != nil {
+		return err
+	}
+
+	err = migrateTables(db, mysqlTables)
+	if err != nil {
+		return err
+	}
+	// 已有的表补充 dirty、dirty_block 字段
+	_, err = db.Exec("ALTER TABLE " + dbTablePrefix + "block_account ADD COLUMN IF NOT EXISTS dirty tinyint(4) NOT NULL DEFAULT 1, ADD INDEX IF NOT EXISTS dirty (dirty), " +
+		"ADD COLUMN IF NOT EXISTS dirty_block int(11) NOT NULL DEFAULT 0")
+	if err != nil {
+		return err
+	}
+	// 交易列表按 (block_num, id) 排序和游标分页
+	_, err = db.Exec("ALTER TABLE " + dbTablePrefix + "block_transactions ADD INDEX IF NOT EXISTS block_num (block_num)")
+	return err
+}
+
+// mysqlTables 各表的建表语句，%s 为带前缀的表名
+var mysqlTables = [][2]string{
+	{"block_number", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		block_num int(11) NOT NULL DEFAULT 0,
+		block_hash varchar(100) NOT NULL DEFAULT '',
+		block_transactions longtext DEFAULT '',
+		response_code int(8) NOT NULL DEFAULT 0,
+		status tinyint(4) NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		UNIQUE KEY block_num (block_num) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"block_transactions", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		block_num int(11) NOT NULL DEFAULT 0,
+		trans_hash varchar(100) NOT NULL DEFAULT '',
+		` + "`from`" + ` varchar(100) NOT NULL,
+		` + "`to`" + ` varchar(100) NOT NULL,
+		input longtext DEFAULT '',
+		decode_input longtext DEFAULT '',
+		is_contract tinyint(4) NOT NULL DEFAULT 0,
+		method_id varchar(20) DEFAULT NULL,
+		output longtext DEFAULT '',
+		decode_output longtext DEFAULT '',
+		status int(10) NOT NULL DEFAULT -1,
+		gas_used varchar(100) NOT NULL DEFAULT '0',
+		import_time bigint(20) unsigned NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		UNIQUE KEY trans_hash (trans_hash) USING BTREE,
+		KEY ` + "`from`" + ` (` + "`from`" + `) USING BTREE,
+		KEY ` + "`to`" + ` (` + "`to`" + `) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"block_account", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		address varchar(100) NOT NULL DEFAULT '',
+		balance int(10) NOT NULL DEFAULT 0,
+		cred int(10) NOT NULL DEFAULT 0,
+		share_num int(10) NOT NULL DEFAULT 0,
+		dirty tinyint(4) NOT NULL DEFAULT 1,
+		dirty_block int(11) NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		UNIQUE KEY address (address) USING BTREE,
+		KEY dirty (dirty) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"register_audit", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		address varchar(100) NOT NULL DEFAULT '',
+		caller varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status int(10) NOT NULL DEFAULT -1,
+		result varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		finished_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		KEY address (address) USING BTREE,
+		KEY tx_hash (tx_hash) USING BTREE,
+		KEY created_at (created_at) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"register_job", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		address varchar(100) NOT NULL DEFAULT '',
+		active_address varchar(100) DEFAULT NULL,
+		status varchar(20) NOT NULL DEFAULT 'pending',
+		attempts int(10) NOT NULL DEFAULT 0,
+		max_attempts int(10) NOT NULL DEFAULT 5,
+		next_run_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		locked_by varchar(100) NOT NULL DEFAULT '',
+		locked_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status int(10) NOT NULL DEFAULT -1,
+		result varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		callback_url varchar(500) NOT NULL DEFAULT '',
+		callback_status int(10) NOT NULL DEFAULT 0,
+		caller varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		created_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		updated_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		finished_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		UNIQUE KEY active_address (active_address) USING BTREE,
+		KEY status_next_run_at (status, next_run_at) USING BTREE,
+		KEY locked_by (locked_by) USING BTREE,
+		KEY address (address) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"role_audit", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		action varchar(20) NOT NULL DEFAULT '',
+		address varchar(100) NOT NULL DEFAULT '',
+		actor varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status int(10) NOT NULL DEFAULT -1,
+		outcome varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		KEY address (address) USING BTREE,
+		KEY created_at (created_at) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+	{"account_history", `
+	CREATE TABLE IF NOT EXISTS %s (
+		id int(11) unsigned NOT NULL AUTO_INCREMENT,
+		address varchar(100) NOT NULL DEFAULT '',
+		block_num int(11) NOT NULL DEFAULT 0,
+		old_balance int(10) NOT NULL DEFAULT 0,
+		new_balance int(10) NOT NULL DEFAULT 0,
+		old_cred int(10) NOT NULL DEFAULT 0,
+		new_cred int(10) NOT NULL DEFAULT 0,
+		old_share_num int(10) NOT NULL DEFAULT 0,
+		new_share_num int(10) NOT NULL DEFAULT 0,
+		created_at bigint(20) unsigned NOT NULL DEFAULT 0,
+		PRIMARY KEY (id),
+		KEY address_created_at (address, created_at) USING BTREE,
+		KEY address_block_num (address, block_num) USING BTREE
+	  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`},
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresStorage PostgreSQL 后端，连接参数复用 DB_HOST、DB_PORT、DB_USERNAME、DB_PASSWORD、DB_DATABASE，
+// SSL 模式由 DB_SSLMODE 指定（默认 disable）
+type postgresStorage struct{}
+
+func (m *postgresStorage) Name() string {
+	return "postgres"
+}
+
+func (m *postgresStorage) Open() (*sql.DB, error) {
+	host := dbHost
+	if dbPort != "" {
+		host += ":" + dbPort
+	}
+	sslMode := dbSSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(dbUsername, dbPassword),
+		Host:     host,
+		Path:     "/" + dbase,
+		RawQuery: "sslmode=" + url.QueryEscape(sslMode),
+	}
+	return sql.Open("postgres", dsn.String())
+}
+
+// Rebind 将 ? 占位符转换为 $1、$2……，反引号转换为双引号，单引号内的字符串保持不变
+func (m *postgresStorage) Rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+			b.WriteByte(c)
+		case quoted:
+			b.WriteByte(c)
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		case c == '`':
+			b.WriteByte('"')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (m *postgresStorage) OnConflictDoNothing(query string) string {
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+// Insert PostgreSQL 驱动不支持 LastInsertId，通过 RETURNING id 取回自增 id，
+// 冲突未插入时没有返回行
+func (m *postgresStorage) Insert(db *sql.DB, query string, args ...interface{}) (int64, bool, error) {
+	var id int64
+	err := db.QueryRow(query+" RETURNING id", args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+func (m *postgresStorage) Migrate(db *sql.DB) error {
+	err := migrateTables(db, postgresTables)
+	if err != nil {
+		return err
+	}
+	// 已有的表补充 dirty_block 字段
+	_, err = db.Exec("ALTER TABLE " + dbTablePrefix + "block_account ADD COLUMN IF NOT EXISTS dirty_block integer NOT NULL DEFAULT 0")
+	return err
+}
+
+// postgresTables 各表的建表语句，%[1]s 为带前缀的表名。
+// from/to 是保留字需要加双引号；decode_input/decode_output 使用 JSONB，未解码时为 NULL；
+// 体征字段在 MariaDB 中由外部维护，这里一并建出以便接口可以直接查询
+var postgresTables = [][2]string{
+	{"block_number", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		block_num integer NOT NULL DEFAULT 0 UNIQUE,
+		block_hash varchar(100) NOT NULL DEFAULT '',
+		block_transactions text DEFAULT '',
+		response_code integer NOT NULL DEFAULT 0,
+		status smallint NOT NULL DEFAULT 0
+	);`},
+	{"block_transactions", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		block_num integer NOT NULL DEFAULT 0,
+		trans_hash varchar(100) NOT NULL DEFAULT '' UNIQUE,
+		"from" varchar(100) NOT NULL,
+		"to" varchar(100) NOT NULL,
+		input text DEFAULT '',
+		decode_input jsonb DEFAULT NULL,
+		is_contract smallint NOT NULL DEFAULT 0,
+		method_id varchar(20) DEFAULT NULL,
+		output text DEFAULT '',
+		decode_output jsonb DEFAULT NULL,
+		status integer NOT NULL DEFAULT -1,
+		gas_used varchar(100) NOT NULL DEFAULT '0',
+		import_time bigint NOT NULL DEFAULT 0,
+		heart_rate varchar(20) NOT NULL DEFAULT '',
+		breath_rate varchar(20) NOT NULL DEFAULT '',
+		sleep_state integer NOT NULL DEFAULT 0,
+		heart_change varchar(100) NOT NULL DEFAULT '',
+		sleep_breathing varchar(100) NOT NULL DEFAULT '',
+		person_id integer NOT NULL DEFAULT 0,
+		contact_name varchar(100) NOT NULL DEFAULT '',
+		contact_identity varchar(100) NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_from ON %[1]s ("from");
+	CREATE INDEX IF NOT EXISTS %[1]s_to ON %[1]s ("to");
+	CREATE INDEX IF NOT EXISTS %[1]s_block_num ON %[1]s (block_num);`},
+	{"block_account", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		address varchar(100) NOT NULL DEFAULT '' UNIQUE,
+		balance integer NOT NULL DEFAULT 0,
+		cred integer NOT NULL DEFAULT 0,
+		share_num integer NOT NULL DEFAULT 0,
+		dirty smallint NOT NULL DEFAULT 1,
+		dirty_block integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_dirty ON %[1]s (dirty);`},
+	{"register_audit", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		address varchar(100) NOT NULL DEFAULT '',
+		caller varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status integer NOT NULL DEFAULT -1,
+		result varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at bigint NOT NULL DEFAULT 0,
+		finished_at bigint NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);
+	CREATE INDEX IF NOT EXISTS %[1]s_tx_hash ON %[1]s (tx_hash);
+	CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at);`},
+	{"register_job", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		address varchar(100) NOT NULL DEFAULT '',
+		active_address varchar(100) DEFAULT NULL UNIQUE,
+		status varchar(20) NOT NULL DEFAULT 'pending',
+		attempts integer NOT NULL DEFAULT 0,
+		max_attempts integer NOT NULL DEFAULT 5,
+		next_run_at bigint NOT NULL DEFAULT 0,
+		locked_by varchar(100) NOT NULL DEFAULT '',
+		locked_at bigint NOT NULL DEFAULT 0,
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status integer NOT NULL DEFAULT -1,
+		result varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		callback_url varchar(500) NOT NULL DEFAULT '',
+		callback_status integer NOT NULL DEFAULT 0,
+		caller varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		created_at bigint NOT NULL DEFAULT 0,
+		updated_at bigint NOT NULL DEFAULT 0,
+		finished_at bigint NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_status_next_run_at ON %[1]s (status, next_run_at);
+	CREATE INDEX IF NOT EXISTS %[1]s_locked_by ON %[1]s (locked_by);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);`},
+	{"role_audit", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		action varchar(20) NOT NULL DEFAULT '',
+		address varchar(100) NOT NULL DEFAULT '',
+		actor varchar(100) NOT NULL DEFAULT '',
+		remote_ip varchar(100) NOT NULL DEFAULT '',
+		request_id varchar(64) NOT NULL DEFAULT '',
+		tx_hash varchar(100) NOT NULL DEFAULT '',
+		receipt_status integer NOT NULL DEFAULT -1,
+		outcome varchar(50) NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at bigint NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);
+	CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at);`},
+	{"account_history", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id bigserial PRIMARY KEY,
+		address varchar(100) NOT NULL DEFAULT '',
+		block_num integer NOT NULL DEFAULT 0,
+		old_balance integer NOT NULL DEFAULT 0,
+		new_balance integer NOT NULL DEFAULT 0,
+		old_cred integer NOT NULL DEFAULT 0,
+		new_cred integer NOT NULL DEFAULT 0,
+		old_share_num integer NOT NULL DEFAULT 0,
+		new_share_num integer NOT NULL DEFAULT 0,
+		created_at bigint NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address_created_at ON %[1]s (address, created_at);
+	CREATE INDEX IF NOT EXISTS %[1]s_address_block_num ON %[1]s (address, block_num);`},
+}
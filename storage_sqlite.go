@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteStorage 内嵌 SQLite 后端（纯 Go 实现，无需 cgo），用于本地开发和 CI，数据文件由 DB_PATH 指定。
+// SQLite 兼容 ? 占位符和反引号写法，业务语句无需转换
+type sqliteStorage struct {
+	path string
+}
+
+func (m *sqliteStorage) Name() string {
+	return "sqlite"
+}
+
+func (m *sqliteStorage) Open() (*sql.DB, error) {
+	// 区块同步、帐户同步和注册任务会并发写入，开启 WAL 并在锁冲突时等待
+	dsn := "file:" + m.path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	return sql.Open("sqlite", dsn)
+}
+
+func (m *sqliteStorage) Rebind(query string) string {
+	return query
+}
+
+func (m *sqliteStorage) OnConflictDoNothing(query string) string {
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+func (m *sqliteStorage) Insert(db *sql.DB, query string, args ...interface{}) (int64, bool, error) {
+	return execInsert(db, query, args...)
+}
+
+func (m *sqliteStorage) Migrate(db *sql.DB) error {
+	err := migrateTables(db, sqliteTables)
+	if err != nil {
+		return err
+	}
+	// 已有的表补充 dirty_block 字段，SQLite 的 ADD COLUMN 不支持 IF NOT EXISTS
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'dirty_block'", dbTablePrefix+"block_account").Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + dbTablePrefix + "block_account ADD COLUMN dirty_block integer NOT NULL DEFAULT 0")
+	return err
+}
+
+// sqliteTables 各表的建表语句，%[1]s 为带前缀的表名。
+// MariaDB 默认排序规则不区分大小写，地址和哈希字段使用 NOCASE 保持一致；
+// 体征字段在 MariaDB 中由外部维护，这里一并建出以便接口可以直接查询
+var sqliteTables = [][2]string{
+	{"block_number", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		block_num integer NOT NULL DEFAULT 0 UNIQUE,
+		block_hash text NOT NULL DEFAULT '',
+		block_transactions text DEFAULT '',
+		response_code integer NOT NULL DEFAULT 0,
+		status integer NOT NULL DEFAULT 0
+	);`},
+	{"block_transactions", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		block_num integer NOT NULL DEFAULT 0,
+		trans_hash text NOT NULL DEFAULT '' COLLATE NOCASE UNIQUE,
+		` + "`from`" + ` text NOT NULL COLLATE NOCASE,
+		` + "`to`" + ` text NOT NULL COLLATE NOCASE,
+		input text DEFAULT '',
+		decode_input text DEFAULT '',
+		is_contract integer NOT NULL DEFAULT 0,
+		method_id text DEFAULT NULL,
+		output text DEFAULT '',
+		decode_output text DEFAULT '',
+		status integer NOT NULL DEFAULT -1,
+		gas_used text NOT NULL DEFAULT '0',
+		import_time integer NOT NULL DEFAULT 0,
+		heart_rate text NOT NULL DEFAULT '',
+		breath_rate text NOT NULL DEFAULT '',
+		sleep_state integer NOT NULL DEFAULT 0,
+		heart_change text NOT NULL DEFAULT '',
+		sleep_breathing text NOT NULL DEFAULT '',
+		person_id integer NOT NULL DEFAULT 0,
+		contact_name text NOT NULL DEFAULT '',
+		contact_identity text NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_from ON %[1]s (` + "`from`" + `);
+	CREATE INDEX IF NOT EXISTS %[1]s_to ON %[1]s (` + "`to`" + `);
+	CREATE INDEX IF NOT EXISTS %[1]s_block_num ON %[1]s (block_num);`},
+	{"block_account", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		address text NOT NULL DEFAULT '' COLLATE NOCASE UNIQUE,
+		balance integer NOT NULL DEFAULT 0,
+		cred integer NOT NULL DEFAULT 0,
+		share_num integer NOT NULL DEFAULT 0,
+		dirty integer NOT NULL DEFAULT 1,
+		dirty_block integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_dirty ON %[1]s (dirty);`},
+	{"register_audit", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		address text NOT NULL DEFAULT '' COLLATE NOCASE,
+		caller text NOT NULL DEFAULT '',
+		remote_ip text NOT NULL DEFAULT '',
+		request_id text NOT NULL DEFAULT '',
+		tx_hash text NOT NULL DEFAULT '' COLLATE NOCASE,
+		receipt_status integer NOT NULL DEFAULT -1,
+		result text NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at integer NOT NULL DEFAULT 0,
+		finished_at integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);
+	CREATE INDEX IF NOT EXISTS %[1]s_tx_hash ON %[1]s (tx_hash);
+	CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at);`},
+	{"register_job", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		address text NOT NULL DEFAULT '' COLLATE NOCASE,
+		active_address text DEFAULT NULL COLLATE NOCASE UNIQUE,
+		status text NOT NULL DEFAULT 'pending',
+		attempts integer NOT NULL DEFAULT 0,
+		max_attempts integer NOT NULL DEFAULT 5,
+		next_run_at integer NOT NULL DEFAULT 0,
+		locked_by text NOT NULL DEFAULT '',
+		locked_at integer NOT NULL DEFAULT 0,
+		tx_hash text NOT NULL DEFAULT '' COLLATE NOCASE,
+		receipt_status integer NOT NULL DEFAULT -1,
+		result text NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		callback_url text NOT NULL DEFAULT '',
+		callback_status integer NOT NULL DEFAULT 0,
+		caller text NOT NULL DEFAULT '',
+		remote_ip text NOT NULL DEFAULT '',
+		request_id text NOT NULL DEFAULT '',
+		created_at integer NOT NULL DEFAULT 0,
+		updated_at integer NOT NULL DEFAULT 0,
+		finished_at integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_status_next_run_at ON %[1]s (status, next_run_at);
+	CREATE INDEX IF NOT EXISTS %[1]s_locked_by ON %[1]s (locked_by);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);`},
+	{"role_audit", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		action text NOT NULL DEFAULT '',
+		address text NOT NULL DEFAULT '' COLLATE NOCASE,
+		actor text NOT NULL DEFAULT '',
+		remote_ip text NOT NULL DEFAULT '',
+		request_id text NOT NULL DEFAULT '',
+		tx_hash text NOT NULL DEFAULT '' COLLATE NOCASE,
+		receipt_status integer NOT NULL DEFAULT -1,
+		outcome text NOT NULL DEFAULT '',
+		error text DEFAULT NULL,
+		created_at integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address ON %[1]s (address);
+	CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at);`},
+	{"account_history", `
+	CREATE TABLE IF NOT EXISTS %[1]s (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		address text NOT NULL DEFAULT '' COLLATE NOCASE,
+		block_num integer NOT NULL DEFAULT 0,
+		old_balance integer NOT NULL DEFAULT 0,
+		new_balance integer NOT NULL DEFAULT 0,
+		old_cred integer NOT NULL DEFAULT 0,
+		new_cred integer NOT NULL DEFAULT 0,
+		old_share_num integer NOT NULL DEFAULT 0,
+		new_share_num integer NOT NULL DEFAULT 0,
+		created_at integer NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS %[1]s_address_created_at ON %[1]s (address, created_at);
+	CREATE INDEX IF NOT EXISTS %[1]s_address_block_num ON %[1]s (address, block_num);`},
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStorage(t *testing.T) {
+	tests := []struct {
+		driver string
+		name   string
+	}{
+		{"", "mysql"},
+		{"mysql", "mysql"},
+		{"mariadb", "mysql"},
+		{"sqlite", "sqlite"},
+		{"postgres", "postgres"},
+		{"postgresql", "postgres"},
+	}
+	for _, tt := range tests {
+		store, err := newStorage(tt.driver)
+		if err != nil {
+			t.Fatalf("newStorage(%q): %v", tt.driver, err)
+		}
+		if store.Name() != tt.name {
+			t.Errorf("newStorage(%q).Name() = %q, want %q", tt.driver, store.Name(), tt.name)
+		}
+	}
+	if _, err := newStorage("oracle"); err == nil {
+		t.Error("newStorage(\"oracle\") should fail")
+	}
+}
+
+func TestStorageRebind(t *testing.T) {
+	query := "SELECT `from` FROM bc_block_transactions WHERE `to` = ? AND input <> '?' LIMIT ? OFFSET ?"
+	tests := []struct {
+		store Storage
+		want  string
+	}{
+		// MySQL 和 SQLite 直接使用业务语句
+		{&mysqlStorage{}, query},
+		{&sqliteStorage{}, query},
+		// 单引号内的 ? 不是占位符
+		{&postgresStorage{}, `SELECT "from" FROM bc_block_transactions WHERE "to" = $1 AND input <> '?' LIMIT $2 OFFSET $3`},
+	}
+	for _, tt := range tests {
+		if got := tt.store.Rebind(query); got != tt.want {
+			t.Errorf("%s Rebind = %q, want %q", tt.store.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	store := &postgresStorage{}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"UPDATE bc_block_account SET dirty = 1 WHERE address IN (?, ?, ?)", "UPDATE bc_block_account SET dirty = 1 WHERE address IN ($1, $2, $3)"},
+		{"SELECT * FROM t WHERE a = 'it''s ?' AND b = ?", "SELECT * FROM t WHERE a = 'it''s ?' AND b = $1"},
+		{"SELECT '`' AS q, `status` FROM t", `SELECT '` + "`" + `' AS q, "status" FROM t`},
+	}
+	for _, tt := range tests {
+		if got := store.Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestStorageOnConflictDoNothing(t *testing.T) {
+	query := "INSERT INTO bc_block_account (address) VALUES (?)"
+	tests := []struct {
+		store Storage
+		want  string
+	}{
+		{&mysqlStorage{}, query + " ON DUPLICATE KEY UPDATE id = id"},
+		{&sqliteStorage{}, query + " ON CONFLICT DO NOTHING"},
+		{&postgresStorage{}, query + " ON CONFLICT DO NOTHING"},
+	}
+	for _, tt := range tests {
+		if got := tt.store.OnConflictDoNothing(query); got != tt.want {
+			t.Errorf("%s OnConflictDoNothing = %q, want %q", tt.store.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	s := newTestSQL(t)
+	// 重复迁移不报错
+	if err := s.store.Migrate(s.db); err != nil {
+		t.Fatalf("second Migrate: %v", err)
+	}
+	for _, table := range sqliteTables {
+		var name string
+		err := s.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", "bc_"+table[0]).Scan(&name)
+		if err != nil {
+			t.Errorf("table bc_%s: %v", table[0], err)
+		}
+	}
+}
+
+func TestSQLiteMigrateAddsDirtyBlock(t *testing.T) {
+	saved := dbTablePrefix
+	dbTablePrefix = "bc_"
+	t.Cleanup(func() { dbTablePrefix = saved })
+
+	store := &sqliteStorage{path: filepath.Join(t.TempDir(), "bc.db")}
+	db, err := store.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// 旧版本的表没有 dirty_block 字段
+	_, err = db.Exec("CREATE TABLE bc_block_account (id integer PRIMARY KEY AUTOINCREMENT, address text NOT NULL DEFAULT '' COLLATE NOCASE UNIQUE, " +
+		"balance integer NOT NULL DEFAULT 0, cred integer NOT NULL DEFAULT 0, share_num integer NOT NULL DEFAULT 0, dirty integer NOT NULL DEFAULT 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO bc_block_account (address) VALUES ('0xa')"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	var dirtyBlock int
+	if err := db.QueryRow("SELECT dirty_block FROM bc_block_account WHERE address = '0xa'").Scan(&dirtyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if dirtyBlock != 0 {
+		t.Errorf("dirty_block = %d, want 0", dirtyBlock)
+	}
+}
+
+func TestSQLiteInsertIgnore(t *testing.T) {
+	s := newTestSQL(t)
+	query := "INSERT INTO bc_block_account (address, balance) VALUES (?, ?)"
+	id, inserted, err := s.InsertIgnore(query, testAddressA, 1)
+	if err != nil || !inserted || id == 0 {
+		t.Fatalf("first InsertIgnore = %d, %v, %v", id, inserted, err)
+	}
+	// 地址不区分大小写，冲突时不修改已有数据
+	id, inserted, err = s.InsertIgnore(query, "0x00000000000000000000000000000000000000AA", 2)
+	if err != nil || inserted || id != 0 {
+		t.Fatalf("duplicate InsertIgnore = %d, %v, %v", id, inserted, err)
+	}
+	var balance int
+	if err := s.QueryRow("SELECT balance FROM bc_block_account WHERE address = ?", testAddressA).Scan(&balance); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 1 {
+		t.Errorf("balance = %d, want 1", balance)
+	}
+
+	if _, _, err := s.Insert(query, testAddressA, 3); err == nil {
+		t.Error("Insert of duplicate address should fail")
+	}
+}
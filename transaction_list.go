@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+var methodIdPattern = regexp.MustCompile("^(0x)?[0-9a-fA-F]{8}$")
+
+// TransactionFilter 交易列表的查询条件。BlockStart/BlockEnd、Start/End 为 0 时不限制；
+// Cursor 不为空时按 (block_num, id) 键集分页，忽略 Offset
+type TransactionFilter struct {
+	From       string
+	To         string
+	Party      string // 发送方或接收方
+	MethodId   string
+	PersonId   int
+	Status     *int
+	BlockStart int
+	BlockEnd   int
+	Start      int64
+	End        int64
+	Ascending  bool
+	Cursor     *TransactionCursor
+	Offset     int
+	Limit      int
+}
+
+// TransactionCursor 上一页最后一条交易的位置
+type TransactionCursor struct {
+	BlockNum int   `json:"b"`
+	Id       int64 `json:"i"`
+}
+
+// TransactionList 交易列表，page 模式返回 page 和 total；cursor 模式返回 next_cursor，total 仅在 with_total=true 时返回
+type TransactionList struct {
+	List       interface{} `json:"params"`
+	Page       int         `json:"page,omitempty"`
+	PageSize   int         `json:"pagesize"`
+	Total      *int        `json:"total,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func encodeTransactionCursor(c TransactionCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTransactionCursor(s string) (*TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	c := &TransactionCursor{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// hasSubject 是否指定了帐户（from/to/party）或人员
+func (f TransactionFilter) hasSubject() bool {
+	return f.From != "" || f.To != "" || f.Party != "" || f.PersonId > 0
+}
+
+// where 拼接除游标以外的查询条件
+func (f TransactionFilter) where() (string, []interface{}) {
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	if f.From != "" {
+		where = append(where, "`from` = ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		where = append(where, "`to` = ?")
+		args = append(args, f.To)
+	}
+	if f.Party != "" {
+		where = append(where, "(`from` = ? OR `to` = ?)")
+		args = append(args, f.Party, f.Party)
+	}
+	if f.MethodId != "" {
+		where = append(where, "method_id = ?")
+		args = append(args, f.MethodId)
+	}
+	if f.PersonId > 0 {
+		where = append(where, "person_id = ?")
+		args = append(args, f.PersonId)
+	}
+	if f.Status != nil {
+		where = append(where, "`status` = ?")
+		args = append(args, *f.Status)
+	}
+	if f.BlockStart > 0 {
+		where = append(where, "block_num >= ?")
+		args = append(args, f.BlockStart)
+	}
+	if f.BlockEnd > 0 {
+		where = append(where, "block_num <= ?")
+		args = append(args, f.BlockEnd)
+	}
+	if f.Start > 0 {
+		where = append(where, "import_time >= ?")
+		args = append(args, f.Start)
+	}
+	if f.End > 0 {
+		where = append(where, "import_time < ?")
+		args = append(args, f.End)
+	}
+	if len(where) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// page 拼接游标条件、排序和分页
+func (f TransactionFilter) page(whereSql string, args []interface{}) (string, []interface{}) {
+	op, order := "<", "DESC"
+	if f.Ascending {
+		op, order = ">", "ASC"
+	}
+	if f.Cursor != nil {
+		cond := "(block_num " + op + " ? OR (block_num = ? AND id " + op + " ?))"
+		if whereSql == "" {
+			whereSql = " WHERE " + cond
+		} else {
+			whereSql += " AND " + cond
+		}
+		args = append(args, f.Cursor.BlockNum, f.Cursor.BlockNum, f.Cursor.Id)
+	}
+	query := whereSql + " ORDER BY block_num " + order + ", id " + order + " LIMIT ?"
+	args = append(args, f.Limit)
+	if f.Cursor == nil && f.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, f.Offset)
+	}
+	return query, args
+}
+
+// methodIdByName 将合约方法名转换为 method_id（不带 0x 的 8 位十六进制），也可以直接传入 method_id
+func methodIdByName(name string) (string, error) {
+	if methodIdPattern.MatchString(name) {
+		return strings.ToLower(strings.TrimPrefix(name, "0x")), nil
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(abiStr))
+	if err != nil {
+		return "", err
+	}
+	method, ok := contractAbi.Methods[name]
+	if !ok {
+		return "", fmt.Errorf("unknown method %q", name)
+	}
+	return hex.EncodeToString(method.ID), nil
+}
+
+// parseTransactionFilter 解析交易列表参数：
+// address/from、to、party（发送方或接收方）、method（方法名或 method_id）、person（person_id）、status、
+// block_start/block_end（含）、start/end（import_time 毫秒时间戳，左闭右开）、order（asc/desc）、
+// cursor 或 page，pagesize。带 cursor 参数（第一页为空值）时 cursorMode 为 true
+func parseTransactionFilter(r *http.Request) (f TransactionFilter, cursorMode bool, err error) {
+	return transactionFilterFromValues(r.URL.Query())
+}
+
+// transactionFilterFromValues 按 parseTransactionFilter 的规则解析参数，gRPC 请求也转换为参数后在这里校验
+func transactionFilterFromValues(queryValues url.Values) (f TransactionFilter, cursorMode bool, err error) {
+	// 地址统一按小写存储和查询，PostgreSQL 的 varchar 比较区分大小写
+	f = TransactionFilter{
+		From:  strings.ToLower(queryValues.Get("from")),
+		To:    strings.ToLower(queryValues.Get("to")),
+		Party: strings.ToLower(queryValues.Get("party")),
+	}
+	if f.From == "" {
+		f.From = strings.ToLower(queryValues.Get("address"))
+	}
+	for _, address := range []string{f.From, f.To, f.Party} {
+		if address != "" && !isValidAddress(address) {
+			return f, false, serviceError(errCodeInvalidAddress, "Invalid address format")
+		}
+	}
+	if v := queryValues.Get("method"); v != "" {
+		methodId, err := methodIdByName(v)
+		if err != nil {
+			return f, false, serviceError(errCodeInvalidParameter, err.Error())
+		}
+		f.MethodId = methodId
+	}
+	if v := queryValues.Get("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil {
+			return f, false, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid status %q", v))
+		}
+		f.Status = &status
+	}
+	intParams := []struct {
+		name string
+		dest *int
+	}{
+		{"person", &f.PersonId},
+		{"block_start", &f.BlockStart},
+		{"block_end", &f.BlockEnd},
+	}
+	for _, p := range intParams {
+		if v := queryValues.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return f, false, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid %s %q", p.name, v))
+			}
+			*p.dest = n
+		}
+	}
+	int64Params := []struct {
+		name string
+		dest *int64
+	}{
+		{"start", &f.Start},
+		{"end", &f.End},
+	}
+	for _, p := range int64Params {
+		if v := queryValues.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return f, false, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid %s %q", p.name, v))
+			}
+			*p.dest = n
+		}
+	}
+	switch queryValues.Get("order") {
+	case "", "desc":
+	case "asc":
+		f.Ascending = true
+	default:
+		return f, false, serviceError(errCodeInvalidParameter, fmt.Sprintf("invalid order %q", queryValues.Get("order")))
+	}
+
+	pageSize, err := strconv.Atoi(queryValues.Get("pagesize"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	f.Limit = pageSize
+	if queryValues.Has("cursor") {
+		if v := queryValues.Get("cursor"); v != "" {
+			f.Cursor, err = decodeTransactionCursor(v)
+			if err != nil {
+				return f, false, serviceError(errCodeInvalidParameter, err.Error())
+			}
+		}
+		return f, true, nil
+	}
+	page, err := strconv.Atoi(queryValues.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	f.Offset = (page - 1) * pageSize
+	return f, false, nil
+}
+
+// listTransactions getTransByAddress 和 getResByAddress 的公共流程，分页逻辑见 transactionPage
+func (srv *Server) listTransactions(w http.ResponseWriter, r *http.Request, list func(f TransactionFilter, pageSize int) (interface{}, []TransactionCursor, error)) {
+	f, cursorMode, err := parseTransactionFilter(r)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	result, err := srv.transactionPage(f, cursorMode, r.URL.Query().Get("with_total") == "true", list)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	response := ResponseList{
+		Data: result,
+		Msg:  "success",
+		Code: 1,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (srv *Server) getTransByAddress(w http.ResponseWriter, r *http.Request) {
+	srv.listTransactions(w, r, func(f TransactionFilter, pageSize int) (interface{}, []TransactionCursor, error) {
+		transactions, err := srv.txs.List(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		cursors := make([]TransactionCursor, len(transactions))
+		for i, tx := range transactions {
+			blockNum, _ := strconv.Atoi(tx.BlockNumber)
+			cursors[i] = TransactionCursor{BlockNum: blockNum, Id: tx.Id}
+		}
+		if len(transactions) > pageSize {
+			transactions = transactions[:pageSize]
+		}
+		return transactions, cursors, nil
+	})
+}
+
+func (srv *Server) getResByAddress(w http.ResponseWriter, r *http.Request) {
+	srv.listTransactions(w, r, func(f TransactionFilter, pageSize int) (interface{}, []TransactionCursor, error) {
+		transactions, err := srv.txs.ListRes(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		cursors := make([]TransactionCursor, len(transactions))
+		for i, tx := range transactions {
+			blockNum, _ := strconv.Atoi(tx.BlockNumber)
+			cursors[i] = TransactionCursor{BlockNum: blockNum, Id: tx.Id}
+		}
+		if len(transactions) > pageSize {
+			transactions = transactions[:pageSize]
+		}
+		return transactions, cursors, nil
+	})
+}
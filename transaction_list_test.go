@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTransactionCursor(t *testing.T) {
+	c := TransactionCursor{BlockNum: 12, Id: 345}
+	decoded, err := decodeTransactionCursor(encodeTransactionCursor(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != c {
+		t.Errorf("decode(encode(%+v)) = %+v", c, decoded)
+	}
+	for _, s := range []string{"!!", "bm90IGpzb24"} {
+		if _, err := decodeTransactionCursor(s); err == nil {
+			t.Errorf("decodeTransactionCursor(%q) should fail", s)
+		}
+	}
+}
+
+func TestTransactionFilterFromValues(t *testing.T) {
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{"address=0x1", errCodeInvalidAddress},
+		{"party=" + testAddressA + "&to=xyz", errCodeInvalidAddress},
+		{"address=" + testAddressA + "&status=ok", errCodeInvalidParameter},
+		{"address=" + testAddressA + "&block_start=-1", errCodeInvalidParameter},
+		{"address=" + testAddressA + "&order=up", errCodeInvalidParameter},
+		{"address=" + testAddressA + "&cursor=!!", errCodeInvalidParameter},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		_, _, err := transactionFilterFromValues(values)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.query)
+			continue
+		}
+		if se := asServiceError(err); se.Code != tt.code {
+			t.Errorf("%s: code = %s, want %s", tt.query, se.Code, tt.code)
+		}
+	}
+
+	values, _ := url.ParseQuery("address=" + testAddressA + "&page=3&pagesize=20&order=asc")
+	f, cursorMode, err := transactionFilterFromValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursorMode || f.From != testAddressA || f.Offset != 40 || f.Limit != 20 || !f.Ascending {
+		t.Errorf("filter = %+v, cursorMode %v", f, cursorMode)
+	}
+	values, _ = url.ParseQuery("person=7&cursor=")
+	if f, cursorMode, err = transactionFilterFromValues(values); err != nil || !cursorMode || f.Cursor != nil {
+		t.Errorf("empty cursor: filter = %+v, cursorMode %v, err %v", f, cursorMode, err)
+	}
+}
+
+func TestTransactionPageRequiresSubject(t *testing.T) {
+	srv := NewServer(newTestSQL(t))
+	values, _ := url.ParseQuery("status=0&start=1")
+	f, cursorMode, err := transactionFilterFromValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = srv.indexedTransactionPage(f, cursorMode, false)
+	if se := asServiceError(err); err == nil || se.Code != errCodeInvalidParameter {
+		t.Errorf("page without subject: err = %v", err)
+	}
+}
+
+func TestTransactionPageCursor(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	srv := NewServer(s)
+
+	for _, order := range []string{"desc", "asc"} {
+		t.Run(order, func(t *testing.T) {
+			want := []string{"0x05", "0x04", "0x03", "0x01"}
+			if order == "asc" {
+				want = []string{"0x01", "0x03", "0x04", "0x05"}
+			}
+			hashes := make([]string, 0)
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatal("cursor paging did not terminate")
+				}
+				values := url.Values{"party": {testAddressA}, "pagesize": {"3"}, "order": {order}, "cursor": {cursor}}
+				f, cursorMode, err := transactionFilterFromValues(values)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err := srv.indexedTransactionPage(f, cursorMode, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Total != nil {
+					t.Errorf("cursor page returned total without with_total")
+				}
+				list := result.List.([]IndexedTransaction)
+				if len(list) > 3 {
+					t.Fatalf("page has %d rows, want at most 3", len(list))
+				}
+				for _, tx := range list {
+					hashes = append(hashes, tx.Hash)
+				}
+				if result.NextCursor == "" {
+					break
+				}
+				cursor = result.NextCursor
+			}
+			assertAddresses(t, hashes, want...)
+		})
+	}
+
+	// 翻页过程中写入的新区块不影响后续页
+	f, _, _ := transactionFilterFromValues(url.Values{"party": {testAddressA}, "pagesize": {"2"}, "cursor": {""}})
+	first, err := srv.indexedTransactionPage(f, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Total == nil || *first.Total != 4 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+	mustExec(t, s, "INSERT INTO bc_block_transactions (block_num, trans_hash, `from`, `to`, `status`) VALUES (4, '0x06', ?, ?, 0)", testAddressA, testAddressB)
+	f, _, _ = transactionFilterFromValues(url.Values{"party": {testAddressA}, "pagesize": {"2"}, "cursor": {first.NextCursor}})
+	second, err := srv.indexedTransactionPage(f, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := second.List.([]IndexedTransaction)
+	if len(list) != 2 || list[0].Hash != "0x03" || list[1].Hash != "0x01" || second.NextCursor != "" {
+		t.Errorf("second page = %+v", second)
+	}
+}
+
+func TestTransactionPageOffset(t *testing.T) {
+	s := newTestSQL(t)
+	seedTransactions(t, s)
+	srv := NewServer(s)
+
+	f, cursorMode, err := transactionFilterFromValues(url.Values{"party": {testAddressA}, "pagesize": {"3"}, "page": {"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := srv.indexedTransactionPage(f, cursorMode, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := result.List.([]IndexedTransaction)
+	if result.Page != 2 || result.Total == nil || *result.Total != 4 || len(list) != 1 || list[0].Hash != "0x01" {
+		t.Errorf("page 2 = %+v", result)
+	}
+}